@@ -0,0 +1,265 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopiper/engine"
+)
+
+// Audiobook is an EPUB or HTML book whose chapters are synthesized as separate jobs
+type Audiobook struct {
+	ID        string             `json:"id"`
+	Title     string             `json:"title"`
+	Status    JobStatus          `json:"status"`
+	Format    string             `json:"format"`
+	Progress  float64            `json:"progress"`
+	Chapters  []AudiobookChapter `json:"chapters"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
+// AudiobookChapter is one chapter and the state of its job
+type AudiobookChapter struct {
+	Index         int       `json:"index"`
+	Title         string    `json:"title"`
+	JobID         string    `json:"jobId"`
+	Status        JobStatus `json:"status"`
+	Progress      float64   `json:"progress"`
+	SentenceCount int       `json:"sentenceCount"`
+	Error         string    `json:"error,omitempty"`
+}
+
+type AudiobookManager struct {
+	books map[string]*Audiobook
+	mu    sync.Mutex
+}
+
+var audiobookManager = NewAudiobookManager()
+
+func NewAudiobookManager() *AudiobookManager {
+	return &AudiobookManager{
+		books: make(map[string]*Audiobook),
+	}
+}
+
+// Characters that can't be used in file names inside the zip
+var unsafeFileNameChars = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]+`)
+
+// Read a book (EPUB or HTML) and prepare a conversion for every chapter with the
+// options of req. The returned status code is meant for the HTTP response when err is not nil.
+func prepareAudiobook(data []byte, req engine.ConvertRequest) (string, []engine.BookChapter, []*engine.Conversion, int, error) {
+	title, chapters, err := engine.ReadBook(data)
+	if err != nil {
+		return "", nil, nil, http.StatusBadRequest, err
+	}
+
+	req.InputType = "html"
+	req.Turns = nil
+
+	kept := []engine.BookChapter{}
+	conversions := []*engine.Conversion{}
+	for i, chapter := range chapters {
+		req.Text = chapter.Text
+		conv, err := ttsEngine.Prepare(req)
+		if err != nil {
+			// Chapters with nothing to read, like a title page, are left out
+			if errors.Is(err, engine.ErrNoSentences) {
+				continue
+			}
+			return "", nil, nil, errorStatus(err), fmt.Errorf("Chapter %d (%s): %v", i+1, chapter.Title, err)
+		}
+		kept = append(kept, chapter)
+		conversions = append(conversions, conv)
+	}
+
+	if len(conversions) == 0 {
+		return "", nil, nil, http.StatusBadRequest, fmt.Errorf("No chapters with text found")
+	}
+	if title == "" {
+		title = kept[0].Title
+	}
+
+	return title, kept, conversions, http.StatusOK, nil
+}
+
+// Start a job for every chapter of a prepared book, on behalf of client
+func (am *AudiobookManager) Create(title string, chapters []engine.BookChapter, conversions []*engine.Conversion, client string) Audiobook {
+	book := &Audiobook{
+		ID:        generateRandomString(8),
+		Title:     title,
+		Format:    conversions[0].Format,
+		Chapters:  make([]AudiobookChapter, len(chapters)),
+		CreatedAt: time.Now(),
+	}
+
+	for i, conv := range conversions {
+		job := jobManager.Create(conv, client)
+		book.Chapters[i] = AudiobookChapter{Index: i, Title: chapters[i].Title, JobID: job.ID}
+	}
+
+	am.mu.Lock()
+	am.prune()
+	am.books[book.ID] = book
+	am.mu.Unlock()
+
+	log.Printf("[AUDIOBOOK] 📚 Created audiobook %s '%s' with %d chapters", book.ID, title, len(chapters))
+	return am.status(*book)
+}
+
+// Get a book with the current state of its chapter jobs
+func (am *AudiobookManager) Get(id string) (Audiobook, bool) {
+	am.mu.Lock()
+	book, ok := am.books[id]
+	var copied Audiobook
+	if ok {
+		copied = *book
+		copied.Chapters = append([]AudiobookChapter{}, book.Chapters...)
+	}
+	am.mu.Unlock()
+
+	if !ok {
+		return Audiobook{}, false
+	}
+	return am.status(copied), true
+}
+
+// Cancel the jobs of every chapter, or discard them with their audio once finished
+func (am *AudiobookManager) Cancel(id string) (Audiobook, bool) {
+	book, ok := am.Get(id)
+	if !ok {
+		return Audiobook{}, false
+	}
+
+	for i, chapter := range book.Chapters {
+		if job, ok := jobManager.Cancel(chapter.JobID); ok {
+			book.Chapters[i].Status = job.Status
+		}
+	}
+	if book.Status != JobCompleted {
+		book.Status = JobCancelled
+	}
+
+	am.mu.Lock()
+	delete(am.books, id)
+	am.mu.Unlock()
+
+	log.Printf("[AUDIOBOOK] 🛑 Cancelled audiobook %s", id)
+	return book, true
+}
+
+// Fill in the chapter states from their jobs. A chapter whose job expired is reported as cancelled.
+func (am *AudiobookManager) status(book Audiobook) Audiobook {
+	counts := make(map[JobStatus]int)
+	sentences, completed := 0, 0.0
+
+	for i := range book.Chapters {
+		chapter := &book.Chapters[i]
+		job, ok := jobManager.Get(chapter.JobID)
+		if !ok {
+			chapter.Status = JobCancelled
+			chapter.Error = "Job expired"
+		} else {
+			chapter.Status = job.Status
+			chapter.Progress = job.Progress
+			chapter.SentenceCount = job.SentenceCount
+			chapter.Error = job.Error
+		}
+		counts[chapter.Status]++
+		sentences += chapter.SentenceCount
+		completed += chapter.Progress * float64(chapter.SentenceCount)
+	}
+
+	switch {
+	case counts[JobCompleted] == len(book.Chapters):
+		book.Status = JobCompleted
+	case counts[JobQueued] == len(book.Chapters):
+		book.Status = JobQueued
+	case counts[JobQueued]+counts[JobRunning] > 0:
+		book.Status = JobRunning
+	case counts[JobFailed] > 0:
+		book.Status = JobFailed
+	default:
+		book.Status = JobCancelled
+	}
+
+	if sentences > 0 {
+		book.Progress = completed / float64(sentences)
+	}
+	return book
+}
+
+// Drop books whose chapter jobs have all expired. Must be called with am.mu held.
+func (am *AudiobookManager) prune() {
+	for id, book := range am.books {
+		expired := true
+		for _, chapter := range book.Chapters {
+			if _, ok := jobManager.Get(chapter.JobID); ok {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			delete(am.books, id)
+		}
+	}
+}
+
+// Write the audio of every chapter of a completed book to a zip, one numbered file per chapter
+func writeAudiobookZip(w io.Writer, book Audiobook) error {
+	archive := zip.NewWriter(w)
+	digits := len(fmt.Sprint(len(book.Chapters)))
+	if digits < 2 {
+		digits = 2
+	}
+
+	for i, chapter := range book.Chapters {
+		audioPath, format, _, ok := jobManager.AudioPath(chapter.JobID)
+		if !ok {
+			return fmt.Errorf("Chapter %d is no longer available", i+1)
+		}
+
+		name := fmt.Sprintf("%0*d %s%s", digits, i+1, safeFileName(chapter.Title), engine.OutputFormats[format].Extension)
+		header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()}
+		if format == "wav" {
+			header.Method = zip.Deflate
+		}
+
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(audioPath)
+		if err != nil {
+			return fmt.Errorf("Chapter %d is no longer available", i+1)
+		}
+		_, err = io.Copy(entry, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Make a title usable as a file name
+func safeFileName(title string) string {
+	name := strings.TrimSpace(unsafeFileNameChars.ReplaceAllString(title, " "))
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > 80 {
+		name = strings.TrimSpace(string(runes[:80]))
+	}
+	if name == "" {
+		name = "audiobook"
+	}
+	return name
+}
@@ -0,0 +1,313 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type AudioSettings struct {
+	Speaker     int     `json:"speaker"`
+	NoiseScale  float64 `json:"noise_scale"`
+	LengthScale float64 `json:"length_scale"`
+	NoiseW      float64 `json:"noise_w"`
+}
+
+type SentenceResult struct {
+	Index     int
+	AudioFile string
+	Sentence  string
+	Error     error
+}
+
+// Generate audio with the configured backend, or else piper through a persistent worker when enabled.
+// Cancelling ctx kills the piper process working on the text.
+func (e *Engine) generateAudio(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	if e.config.Backend != nil {
+		return e.config.Backend.Synthesize(ctx, text, modelPath, settings)
+	}
+
+	if e.PersistentWorkers() {
+		outputFile, err := e.workers.Synthesize(ctx, text, modelPath, settings)
+		if err == nil || ctx.Err() != nil {
+			return outputFile, err
+		}
+		log.Printf("[WORKERS] ⚠️  Worker failed, falling back to a single piper run: %v", err)
+	}
+
+	return e.cli.Synthesize(ctx, text, modelPath, settings)
+}
+
+// PiperCLI is the backend that starts a new piper process for every sentence
+type PiperCLI struct {
+	// Path of the piper executable
+	Path string
+	// Directory with piper's shared libraries, put first in LD_LIBRARY_PATH
+	LibraryDir string
+}
+
+// Generate audio by starting a new piper process for this text only.
+// The process is killed, and its output removed, when ctx is cancelled.
+func (pc *PiperCLI) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	outputFile := filepath.Join(os.TempDir(), fmt.Sprintf("tts_%s.wav", generateRandomString(8)))
+
+	args := []string{
+		"-m", modelPath,
+		"-f", outputFile,
+		"--speaker", strconv.Itoa(settings.Speaker),
+		"--noise-scale", fmt.Sprintf("%.3f", settings.NoiseScale),
+		"--length-scale", fmt.Sprintf("%.3f", settings.LengthScale),
+		"--noise-w", fmt.Sprintf("%.3f", settings.NoiseW),
+	}
+
+	log.Printf("Piper command: %s %v", pc.Path, args)
+	log.Printf("Input text: %s", text)
+
+	cmd := pc.Command(ctx, args...)
+
+	// Create stdin pipe
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("error creating stdin pipe: %v", err)
+	}
+
+	// Capture stderr
+	stderr := &tailBuffer{max: 4096}
+	cmd.Stderr = stderr
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("error starting piper: %v", err)
+	}
+
+	// Write text to stdin
+	if _, err := stdin.Write([]byte(text)); err != nil {
+		// Piper exited early, or was killed; reap it before giving up
+		stdin.Close()
+		cmd.Wait()
+		os.Remove(outputFile)
+		if ctx.Err() != nil {
+			return "", &killedRunError{Err: ctx.Err(), Stderr: stderr.String()}
+		}
+		return "", &PiperError{Err: fmt.Errorf("error writing to stdin: %v", err), Stderr: stderr.String()}
+	}
+	stdin.Close()
+
+	// Wait for command to finish
+	if err := cmd.Wait(); err != nil {
+		os.Remove(outputFile)
+		if ctx.Err() != nil {
+			return "", &killedRunError{Err: ctx.Err(), Stderr: stderr.String()}
+		}
+		return "", &PiperError{Err: err, Stderr: stderr.String()}
+	}
+
+	// Check if output file exists
+	if _, err := os.Stat(outputFile); os.IsNotExist(err) {
+		return "", &PiperError{Err: fmt.Errorf("output file not created: %s", outputFile), Stderr: stderr.String()}
+	}
+
+	return outputFile, nil
+}
+
+// Build a piper command with the environment it needs to find its shared libraries.
+// The process is killed if ctx is cancelled before it exits.
+func (pc *PiperCLI) Command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, pc.Path, args...)
+	// Don't hang on pipes still held open by children of a killed process
+	cmd.WaitDelay = 2 * time.Second
+
+	// Set LD_LIBRARY_PATH for Linux to find shared libraries
+	if libraryDir := pc.LibraryDir; libraryDir != "" {
+		// Get current environment
+		env := os.Environ()
+		
+		// Get existing LD_LIBRARY_PATH
+		existingPath := os.Getenv("LD_LIBRARY_PATH")
+		
+		// Build new LD_LIBRARY_PATH with the piper library directory first
+		var newPath string
+		if existingPath != "" {
+			newPath = libraryDir + ":" + existingPath
+		} else {
+			newPath = libraryDir + ":/usr/local/lib:/usr/lib:/lib"
+		}
+		
+		// Add LD_LIBRARY_PATH to command environment
+		env = append(env, "LD_LIBRARY_PATH="+newPath)
+		cmd.Env = env
+		
+		log.Printf("[LIBRARY] Setting LD_LIBRARY_PATH to: %s", newPath)
+	}
+
+	return cmd
+}
+
+// Generate audio for multiple sentences in parallel.
+// progress (optional) is called from worker goroutines as each sentence finishes.
+// Once ctx is cancelled, sentences still waiting in the queue are dropped, running piper
+// processes are killed and the audio of the finished sentences is removed.
+func (e *Engine) generateAudioParallel(ctx context.Context, items []SynthItem, progress func(SentenceResult)) ([]string, error) {
+	queueStatus := e.queue.GetStatus()
+	log.Printf("[PARALLEL] Processing %d sentences with max %d concurrent processes", len(items), queueStatus.MaxConcurrent)
+	log.Printf("[PARALLEL] Queue status - Running: %d, Queued: %d", queueStatus.Running, queueStatus.Queued)
+
+	results := make([]SentenceResult, len(items))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i, item := range items {
+		wg.Add(1)
+		index := i
+		sent := item.Text
+		modelPath := item.Model.OnnxPath
+		settings := item.Settings
+
+		go func() {
+			defer wg.Done()
+
+			log.Printf("[PARALLEL] Starting sentence %d/%d: \"%s...\"", index+1, len(items), TruncateString(sent, 50))
+
+			// Reuse a cached rendering of this sentence when there is one
+			cacheKey := ""
+			if e.cache != nil {
+				if key, err := sentenceCacheKey(e.backendName(), sent, modelPath, settings); err == nil {
+					cacheKey = key
+				}
+			}
+
+			var result interface{}
+			var err error
+			if cachedFile, ok := e.lookupSentenceCache(cacheKey); ok {
+				log.Printf("[PARALLEL] Cache hit for sentence %d/%d", index+1, len(items))
+				result = cachedFile
+			} else {
+				// Queue the sentence, again if piper fails
+				var attempts int
+				result, attempts, err = e.synthesizeSentence(ctx, sent, modelPath, settings)
+				if err != nil {
+					err = &SentenceError{Index: index, Sentence: sent, Attempts: attempts, Err: err}
+				}
+
+				if err == nil && cacheKey != "" {
+					e.cache.Put(cacheKey, result.(string))
+				}
+			}
+
+			var sentenceResult SentenceResult
+			if err != nil {
+				log.Printf("[PARALLEL] ❌ %v", err)
+				sentenceResult = SentenceResult{
+					Index:    index,
+					Sentence: sent,
+					Error:    err,
+				}
+			} else {
+				audioFile := result.(string)
+				log.Printf("[PARALLEL] Completed sentence %d/%d", index+1, len(items))
+				sentenceResult = SentenceResult{
+					Index:     index,
+					AudioFile: audioFile,
+					Sentence:  sent,
+					Error:     nil,
+				}
+			}
+
+			mu.Lock()
+			results[index] = sentenceResult
+			mu.Unlock()
+
+			if progress != nil {
+				progress(sentenceResult)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Check for errors and collect audio files
+	audioFiles := []string{}
+	var firstErr error
+	for _, result := range results {
+		if result.Error != nil {
+			if firstErr == nil {
+				firstErr = result.Error
+			}
+			continue
+		}
+		audioFiles = append(audioFiles, result.AudioFile)
+	}
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+
+	if firstErr != nil {
+		// Clean up any generated files
+		for _, file := range audioFiles {
+			os.Remove(file)
+		}
+		return nil, firstErr
+	}
+
+	log.Printf("[PARALLEL] All %d sentences processed successfully", len(items))
+	return audioFiles, nil
+}
+
+func (e *Engine) lookupSentenceCache(key string) (string, bool) {
+	if e.cache == nil || key == "" {
+		return "", false
+	}
+	return e.cache.Get(key)
+}
+
+// Concatenate multiple audio files using native Go.
+// options sets the pauses, output format, trimming and crossfades.
+// Returns where each file ended up in the output.
+func concatenateAudio(audioFiles []string, options JoinOptions, outputPath string) ([]AudioSpan, error) {
+	// Use native Go concatenation only
+	spans, err := concatenateAudioNative(audioFiles, options, outputPath)
+	if err != nil {
+		log.Printf("[CONCAT] ❌ Native concatenation failed: %v", err)
+		return nil, fmt.Errorf("audio concatenation failed: %v", err)
+	}
+	
+	log.Printf("[CONCAT] ✅ Native Go concatenation successful")
+	return spans, nil
+}
+
+// Default piper settings, used for whatever a request leaves out
+func DefaultAudioSettings() AudioSettings {
+	return AudioSettings{
+		Speaker:     0,
+		NoiseScale:  0.667,
+		LengthScale: 1.0,
+		NoiseW:      0.8,
+	}
+}
+
+// Parse audio settings from request
+func parseAudioSettings(data map[string]interface{}) AudioSettings {
+	settings := DefaultAudioSettings()
+
+	if speaker, ok := data["speaker"].(float64); ok {
+		settings.Speaker = int(speaker)
+	}
+	if noiseScale, ok := data["noise_scale"].(float64); ok {
+		settings.NoiseScale = noiseScale
+	}
+	if lengthScale, ok := data["length_scale"].(float64); ok {
+		settings.LengthScale = lengthScale
+	}
+	if noiseW, ok := data["noise_w"].(float64); ok {
+		settings.NoiseW = noiseW
+	}
+
+	return settings
+}
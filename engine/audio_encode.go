@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// An audio format that conversions can be delivered in
+type OutputFormat struct {
+	Extension   string
+	ContentType string
+	// Bitrates in kbps; all zero for lossless formats
+	DefaultBitrate int
+	MinBitrate     int
+	MaxBitrate     int
+}
+
+var OutputFormats = map[string]OutputFormat{
+	"wav":  {Extension: ".wav", ContentType: "audio/wav"},
+	"flac": {Extension: ".flac", ContentType: "audio/flac"},
+	"opus": {Extension: ".ogg", ContentType: "audio/ogg", DefaultBitrate: 32, MinBitrate: 6, MaxBitrate: 510},
+	"mp3":  {Extension: ".mp3", ContentType: "audio/mpeg", DefaultBitrate: 64, MinBitrate: 8, MaxBitrate: 320},
+}
+
+// Other names accepted for the formats above
+var outputFormatAliases = map[string]string{
+	"ogg":  "opus",
+	"mpeg": "mp3",
+}
+
+// A command line tool that can encode WAV into a lossy format
+type externalEncoder struct {
+	name string
+	args func(input, output string, bitrate int) []string
+}
+
+// Encoders for the lossy formats, in order of preference.
+// There is no pure Go Opus or MP3 encoder we can rely on, so these formats need one installed.
+var externalEncoders = map[string][]externalEncoder{
+	"opus": {
+		{"opusenc", func(input, output string, bitrate int) []string {
+			return []string{"--quiet", "--bitrate", strconv.Itoa(bitrate), input, output}
+		}},
+		{"ffmpeg", func(input, output string, bitrate int) []string {
+			return ffmpegArgs(input, output, "libopus", bitrate)
+		}},
+	},
+	"mp3": {
+		{"lame", func(input, output string, bitrate int) []string {
+			return []string{"--quiet", "--abr", strconv.Itoa(bitrate), input, output}
+		}},
+		{"ffmpeg", func(input, output string, bitrate int) []string {
+			return ffmpegArgs(input, output, "libmp3lame", bitrate)
+		}},
+	},
+}
+
+func ffmpegArgs(input, output, codec string, bitrate int) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		"-c:a", codec,
+		"-b:a", fmt.Sprintf("%dk", bitrate),
+		output,
+	}
+}
+
+// Validate a requested format and bitrate, filling in the defaults.
+// Lossy formats also fail here when no encoder for them is installed.
+func ResolveOutputFormat(format string, bitrate int) (string, int, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = "wav"
+	}
+	if alias, ok := outputFormatAliases[format]; ok {
+		format = alias
+	}
+
+	outputFormat, ok := OutputFormats[format]
+	if !ok {
+		return "", 0, fmt.Errorf("Unsupported format: %s (use wav, flac, opus or mp3)", format)
+	}
+
+	if outputFormat.DefaultBitrate == 0 {
+		return format, 0, nil
+	}
+
+	if bitrate == 0 {
+		bitrate = outputFormat.DefaultBitrate
+	}
+	if bitrate < outputFormat.MinBitrate || bitrate > outputFormat.MaxBitrate {
+		return "", 0, fmt.Errorf("Bitrate for %s must be between %d and %d kbps", format, outputFormat.MinBitrate, outputFormat.MaxBitrate)
+	}
+
+	if _, _, err := findExternalEncoder(format); err != nil {
+		return "", 0, err
+	}
+
+	return format, bitrate, nil
+}
+
+func findExternalEncoder(format string) (string, externalEncoder, error) {
+	names := []string{}
+	for _, encoder := range externalEncoders[format] {
+		if path, err := exec.LookPath(encoder.name); err == nil {
+			return path, encoder, nil
+		}
+		names = append(names, encoder.name)
+	}
+	return "", externalEncoder{}, fmt.Errorf("%s output requires %s to be installed", format, strings.Join(names, " or "))
+}
+
+// Encode a WAV file into the given format and return the new file.
+// The WAV file is left in place; for "wav" it is returned as is.
+func encodeAudio(wavPath, format string, bitrate int) (string, error) {
+	if format == "wav" {
+		return wavPath, nil
+	}
+
+	outputFormat, ok := OutputFormats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+	outputPath := strings.TrimSuffix(wavPath, ".wav") + outputFormat.Extension
+
+	if format == "flac" {
+		buffer, header, err := ReadWAVFile(wavPath)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFLACFile(outputPath, buffer, header); err != nil {
+			os.Remove(outputPath)
+			return "", err
+		}
+		logEncodedSize(wavPath, outputPath, format)
+		return outputPath, nil
+	}
+
+	encoderPath, encoder, err := findExternalEncoder(format)
+	if err != nil {
+		return "", err
+	}
+
+	args := encoder.args(wavPath, outputPath, bitrate)
+	log.Printf("[ENCODE] 🔧 Running %s %v", encoder.name, args)
+
+	output, err := exec.Command(encoderPath, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(outputPath)
+		return "", fmt.Errorf("%s failed: %v - %s", encoder.name, err, strings.TrimSpace(string(output)))
+	}
+
+	logEncodedSize(wavPath, outputPath, format)
+	return outputPath, nil
+}
+
+func logEncodedSize(wavPath, outputPath, format string) {
+	wavInfo, err1 := os.Stat(wavPath)
+	outInfo, err2 := os.Stat(outputPath)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	log.Printf("[ENCODE] ✅ Encoded %s: %dKB -> %dKB", format, wavInfo.Size()/1024, outInfo.Size()/1024)
+}
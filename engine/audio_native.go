@@ -0,0 +1,393 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// WAV file header structure
+type WAVHeader struct {
+	SampleRate   uint32
+	NumChannels  uint16
+	BitsPerSample uint16
+	DataSize     uint32
+}
+
+// Read WAV file and return audio data
+func ReadWAVFile(filePath string) (*audio.IntBuffer, *WAVHeader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening WAV file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := wav.NewDecoder(file)
+	if !decoder.IsValidFile() {
+		return nil, nil, fmt.Errorf("invalid WAV file")
+	}
+
+	// Get format info
+	format := decoder.Format()
+	header := &WAVHeader{
+		SampleRate:    uint32(format.SampleRate),
+		NumChannels:   uint16(format.NumChannels),
+		BitsPerSample: uint16(decoder.BitDepth),
+	}
+
+	// Read all audio data
+	buf, err := decoder.FullPCMBuffer()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading PCM data: %v", err)
+	}
+
+	return buf, header, nil
+}
+
+// Write WAV file from audio buffer
+func writeWAVFile(filePath string, buffer *audio.IntBuffer, header *WAVHeader) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("error creating WAV file: %v", err)
+	}
+	defer file.Close()
+
+	encoder := wav.NewEncoder(file, int(header.SampleRate), int(header.BitsPerSample), int(header.NumChannels), 1)
+	
+	if err := encoder.Write(buffer); err != nil {
+		return fmt.Errorf("error writing WAV data: %v", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error closing encoder: %v", err)
+	}
+
+	return nil
+}
+
+// Write a WAV header for a stream of unknown length.
+// The RIFF and data sizes are set to the maximum value, which players treat as "read until EOF".
+func WriteStreamingWAVHeader(w io.Writer, header *WAVHeader) error {
+	blockAlign := uint32(header.NumChannels) * uint32(header.BitsPerSample) / 8
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(0xFFFFFFFF))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, header.NumChannels)
+	binary.Write(&buf, binary.LittleEndian, header.SampleRate)
+	binary.Write(&buf, binary.LittleEndian, header.SampleRate*blockAlign)
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, header.BitsPerSample)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(0xFFFFFFFF))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Encode samples as little-endian PCM bytes, as stored in a WAV data chunk
+func PCMBytes(buffer *audio.IntBuffer, bitsPerSample int) []byte {
+	bytesPerSample := bitsPerSample / 8
+	out := make([]byte, len(buffer.Data)*bytesPerSample)
+
+	for i, sample := range buffer.Data {
+		offset := i * bytesPerSample
+		switch bytesPerSample {
+		case 1:
+			// 8-bit WAV samples are unsigned
+			out[offset] = byte(sample)
+		case 2:
+			binary.LittleEndian.PutUint16(out[offset:], uint16(int16(sample)))
+		case 3:
+			out[offset] = byte(sample)
+			out[offset+1] = byte(sample >> 8)
+			out[offset+2] = byte(sample >> 16)
+		case 4:
+			binary.LittleEndian.PutUint32(out[offset:], uint32(int32(sample)))
+		}
+	}
+
+	return out
+}
+
+// Where a piece of audio sits in a longer file
+type AudioSpan struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// How pieces of audio are joined together
+type JoinOptions struct {
+	// Silence after each piece
+	Pauses []time.Duration
+	// Output format; nil or zero fields keep the format of the first piece
+	Target *WAVHeader
+	// Cut leading and trailing audio quieter than TrimThreshold (linear, 0-1) from each piece
+	TrimSilence   bool
+	TrimThreshold float64
+	// Overlap of pieces that follow each other directly, or fade length next to pauses
+	Crossfade time.Duration
+}
+
+// Audio kept around the loud part of a piece when trimming, so word edges aren't clipped
+const trimMargin = 20 * time.Millisecond
+
+// Concatenate multiple WAV files into one, converting them to a common format and
+// trimming, crossfading and separating them with silence as set in options.
+// Returns the span of each file in the output, not counting the pause after it.
+func concatenateAudioNative(audioFiles []string, options JoinOptions, outputPath string) ([]AudioSpan, error) {
+	if len(audioFiles) == 0 {
+		return nil, fmt.Errorf("no audio files to concatenate")
+	}
+
+	var header *WAVHeader
+	spans := make([]AudioSpan, 0, len(audioFiles))
+	combinedData := []int{}
+	previousFrames := 0
+
+	for i, audioFile := range audioFiles {
+		buffer, fileHeader, err := ReadWAVFile(audioFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %v", audioFile, err)
+		}
+
+		// The first file decides the output format, unless a target was requested
+		if header == nil {
+			header = resolveTargetHeader(fileHeader, options.Target)
+		}
+		channels := int(header.NumChannels)
+
+		// Voices can differ in sample rate, channels and bit depth
+		data := convertAudioFormat(buffer.Data, fileHeader, header)
+
+		// Pieces that follow each other directly are overlapped, the others fade next to the silence
+		directJoin := i > 0 && pauseAt(options.Pauses, i-1) == 0
+		fadeIn := i > 0 && !directJoin
+		fadeOut := i < len(audioFiles)-1 && pauseAt(options.Pauses, i) > 0
+		data = shapePiece(data, header, options, fadeIn, fadeOut)
+
+		overlap := 0
+		if directJoin {
+			overlap = durationFrames(options.Crossfade, header)
+			if limit := minInt(previousFrames, len(data)/channels) / 2; overlap > limit {
+				overlap = limit
+			}
+		}
+
+		start := framesDuration(len(combinedData)/channels-overlap, header)
+		data = crossfadeInto(combinedData, data, overlap, header)
+		combinedData = append(combinedData, data...)
+		spans = append(spans, AudioSpan{Start: start, End: framesDuration(len(combinedData)/channels, header)})
+		combinedData = append(combinedData, silenceSamples(header, pauseAt(options.Pauses, i))...)
+
+		previousFrames = len(data)/channels + overlap
+	}
+
+	// Create combined buffer
+	combinedBuffer := &audio.IntBuffer{
+		Data: combinedData,
+		Format: &audio.Format{
+			NumChannels: int(header.NumChannels),
+			SampleRate:  int(header.SampleRate),
+		},
+		SourceBitDepth: int(header.BitsPerSample),
+	}
+
+	// Write combined file
+	if err := writeWAVFile(outputPath, combinedBuffer, header); err != nil {
+		return nil, err
+	}
+
+	// Clean up individual files
+	for _, file := range audioFiles {
+		os.Remove(file)
+	}
+
+	return spans, nil
+}
+
+// Duration of a number of sample frames (one sample per channel)
+func framesDuration(frames int, header *WAVHeader) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(header.SampleRate)
+}
+
+// Duration of the audio in a WAV file
+func wavDuration(filePath string) (time.Duration, error) {
+	buffer, header, err := ReadWAVFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return framesDuration(len(buffer.Data)/int(header.NumChannels), header), nil
+}
+
+// Build the samples of a silence in the given format
+func silenceSamples(header *WAVHeader, duration time.Duration) []int {
+	frames := int(duration.Seconds() * float64(header.SampleRate))
+	if frames <= 0 {
+		return nil
+	}
+
+	samples := make([]int, frames*int(header.NumChannels))
+
+	// 8-bit WAV is unsigned, so silence sits in the middle of the range
+	if header.BitsPerSample == 8 {
+		for i := range samples {
+			samples[i] = 128
+		}
+	}
+
+	return samples
+}
+
+// Trim a piece and fade its edges as set in options
+func shapePiece(data []int, header *WAVHeader, options JoinOptions, fadeIn, fadeOut bool) []int {
+	if options.TrimSilence {
+		data = trimSilence(data, header, options.TrimThreshold)
+	}
+
+	fade := durationFrames(options.Crossfade, header)
+	if fade > 0 {
+		channels := int(header.NumChannels)
+		if limit := len(data) / channels / 2; fade > limit {
+			fade = limit
+		}
+		center := sampleCenter(header)
+		for i := 0; i < fade; i++ {
+			gain := float64(i) / float64(fade)
+			for ch := 0; ch < channels; ch++ {
+				if fadeIn {
+					index := i*channels + ch
+					data[index] = center + int(float64(data[index]-center)*gain)
+				}
+				if fadeOut {
+					index := len(data) - (i+1)*channels + ch
+					data[index] = center + int(float64(data[index]-center)*gain)
+				}
+			}
+		}
+	}
+
+	return data
+}
+
+// Cut leading and trailing audio below threshold (a fraction of full scale)
+func trimSilence(data []int, header *WAVHeader, threshold float64) []int {
+	channels := int(header.NumChannels)
+	frames := len(data) / channels
+	center := sampleCenter(header)
+	limit := int(threshold * float64(int64(1)<<uint(header.BitsPerSample-1)))
+
+	loud := func(frame int) bool {
+		for ch := 0; ch < channels; ch++ {
+			value := data[frame*channels+ch] - center
+			if value > limit || value < -limit {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := 0
+	for first < frames && !loud(first) {
+		first++
+	}
+	if first == frames {
+		return data[:0]
+	}
+	last := frames - 1
+	for last > first && !loud(last) {
+		last--
+	}
+
+	margin := durationFrames(trimMargin, header)
+	first = maxInt(first-margin, 0)
+	last = minInt(last+margin, frames-1)
+
+	return data[first*channels : (last+1)*channels]
+}
+
+// Mix the first overlap frames of next into the end of combined with an
+// equal-power crossfade, and return the rest of next
+func crossfadeInto(combined, next []int, overlap int, header *WAVHeader) []int {
+	if overlap <= 0 {
+		return next
+	}
+
+	channels := int(header.NumChannels)
+	center := sampleCenter(header)
+	tail := combined[len(combined)-overlap*channels:]
+
+	for i := 0; i < overlap; i++ {
+		position := (float64(i) + 0.5) / float64(overlap) * math.Pi / 2
+		fadeOut, fadeIn := math.Cos(position), math.Sin(position)
+		for ch := 0; ch < channels; ch++ {
+			index := i*channels + ch
+			mixed := float64(tail[index]-center)*fadeOut + float64(next[index]-center)*fadeIn
+			tail[index] = center + clampSample(int(math.Round(mixed)), header)
+		}
+	}
+
+	return next[overlap*channels:]
+}
+
+// Clip a centered sample to the range of the bit depth
+func clampSample(value int, header *WAVHeader) int {
+	limit := int(int64(1) << uint(header.BitsPerSample-1))
+	if value >= limit {
+		return limit - 1
+	}
+	if value < -limit {
+		return -limit
+	}
+	return value
+}
+
+// Value of a silent sample: 8-bit WAV is unsigned
+func sampleCenter(header *WAVHeader) int {
+	if header.BitsPerSample == 8 {
+		return 128
+	}
+	return 0
+}
+
+// Number of sample frames in a duration
+func durationFrames(duration time.Duration, header *WAVHeader) int {
+	return int(duration.Seconds() * float64(header.SampleRate))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Append silence to a decoded buffer
+func appendSilence(buffer *audio.IntBuffer, header *WAVHeader, duration time.Duration) {
+	buffer.Data = append(buffer.Data, silenceSamples(header, duration)...)
+}
+
+func pauseAt(pauses []time.Duration, index int) time.Duration {
+	if index < len(pauses) {
+		return pauses[index]
+	}
+	return 0
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-audio/audio"
+)
+
+var testJoinHeader = &WAVHeader{SampleRate: 22050, NumChannels: 1, BitsPerSample: 16}
+
+// A piece of speech: silence, a loud square wave, and silence again
+func testPiece(lead, loud, tail time.Duration) []int {
+	data := make([]int, 0, durationFrames(lead+loud+tail, testJoinHeader))
+	data = append(data, make([]int, durationFrames(lead, testJoinHeader))...)
+	for i := 0; i < durationFrames(loud, testJoinHeader); i++ {
+		if i%2 == 0 {
+			data = append(data, 10000)
+		} else {
+			data = append(data, -10000)
+		}
+	}
+	return append(data, make([]int, durationFrames(tail, testJoinHeader))...)
+}
+
+// Write pieces as WAV files and join them, returning the output frames and spans
+func joinTestPieces(t *testing.T, pieces [][]int, options JoinOptions) ([]int, []AudioSpan) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := []string{}
+	for i, data := range pieces {
+		file := filepath.Join(dir, fmt.Sprintf("piece%d.wav", i))
+		buffer := &audio.IntBuffer{
+			Data:           append([]int{}, data...),
+			Format:         &audio.Format{NumChannels: 1, SampleRate: int(testJoinHeader.SampleRate)},
+			SourceBitDepth: 16,
+		}
+		if err := writeWAVFile(file, buffer, testJoinHeader); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	outputPath := filepath.Join(dir, "joined.wav")
+	spans, err := concatenateAudioNative(files, options, outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, _, err := ReadWAVFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Data, spans
+}
+
+func TestJoinLengths(t *testing.T) {
+	piece := testPiece(100*time.Millisecond, 300*time.Millisecond, 100*time.Millisecond)
+	frames := len(piece)
+	loudFrames := durationFrames(300*time.Millisecond, testJoinHeader)
+	margin := durationFrames(trimMargin, testJoinHeader)
+	pause := 200 * time.Millisecond
+	pauseFrames := durationFrames(pause, testJoinHeader)
+	crossfade := 50 * time.Millisecond
+	overlap := durationFrames(crossfade, testJoinHeader)
+
+	tests := []struct {
+		name    string
+		options JoinOptions
+		// Length of the output, and where the second piece starts
+		want       int
+		wantSecond int
+	}{
+		{
+			name:       "pauses only",
+			options:    JoinOptions{Pauses: []time.Duration{pause, 0, 0}},
+			want:       3*frames + pauseFrames,
+			wantSecond: frames + pauseFrames,
+		},
+		{
+			name:       "trimmed",
+			options:    JoinOptions{Pauses: []time.Duration{pause, 0, 0}, TrimSilence: true, TrimThreshold: 0.01},
+			want:       3*(loudFrames+2*margin) + pauseFrames,
+			wantSecond: loudFrames + 2*margin + pauseFrames,
+		},
+		{
+			name:       "crossfaded",
+			options:    JoinOptions{Pauses: []time.Duration{0, 0, 0}, Crossfade: crossfade},
+			want:       3*frames - 2*overlap,
+			wantSecond: frames - overlap,
+		},
+		{
+			// Fades next to a pause shape the pieces without overlapping them
+			name:       "faded next to pauses",
+			options:    JoinOptions{Pauses: []time.Duration{pause, pause, 0}, Crossfade: crossfade},
+			want:       3*frames + 2*pauseFrames,
+			wantSecond: frames + pauseFrames,
+		},
+		{
+			name:       "trimmed and crossfaded",
+			options:    JoinOptions{TrimSilence: true, TrimThreshold: 0.01, Crossfade: crossfade},
+			want:       3*(loudFrames+2*margin) - 2*overlap,
+			wantSecond: loudFrames + 2*margin - overlap,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, spans := joinTestPieces(t, [][]int{piece, piece, piece}, tt.options)
+			if len(data) != tt.want {
+				t.Errorf("got %d frames, want %d", len(data), tt.want)
+			}
+			if len(spans) != 3 {
+				t.Fatalf("got %d spans", len(spans))
+			}
+			if want := framesDuration(tt.wantSecond, testJoinHeader); spans[1].Start != want {
+				t.Errorf("second piece starts at %v, want %v", spans[1].Start, want)
+			}
+			if end := framesDuration(len(data), testJoinHeader); spans[2].End != end {
+				t.Errorf("last piece ends at %v, want the end of the audio at %v", spans[2].End, end)
+			}
+		})
+	}
+}
+
+func TestJoinCrossfadeLongerThanPieces(t *testing.T) {
+	short := testPiece(0, 10*time.Millisecond, 0)
+	silent := make([]int, durationFrames(30*time.Millisecond, testJoinHeader))
+	frames := len(short)
+
+	tests := []struct {
+		name    string
+		pieces  [][]int
+		options JoinOptions
+		want    int
+	}{
+		{
+			// Overlaps are cut to half a piece
+			name:    "overlapped",
+			pieces:  [][]int{short, short, short},
+			options: JoinOptions{Crossfade: 500 * time.Millisecond},
+			want:    3*frames - 2*(frames/2),
+		},
+		{
+			name:    "faded",
+			pieces:  [][]int{short, short, short},
+			options: JoinOptions{Pauses: []time.Duration{time.Millisecond, time.Millisecond}, Crossfade: 500 * time.Millisecond},
+			want:    3*frames + 2*durationFrames(time.Millisecond, testJoinHeader),
+		},
+		{
+			// A silent piece is trimmed away entirely
+			name:    "trimmed to nothing",
+			pieces:  [][]int{short, silent, short},
+			options: JoinOptions{TrimSilence: true, TrimThreshold: 0.01, Crossfade: 500 * time.Millisecond},
+			want:    2 * frames,
+		},
+		{
+			name:    "single frame",
+			pieces:  [][]int{{5000}, {5000}},
+			options: JoinOptions{Crossfade: 500 * time.Millisecond},
+			want:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := joinTestPieces(t, tt.pieces, tt.options)
+			if len(data) != tt.want {
+				t.Errorf("got %d frames, want %d", len(data), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPunctuationPauses(t *testing.T) {
+	pauses := map[string]time.Duration{
+		".":   300 * time.Millisecond,
+		"?":   500 * time.Millisecond,
+		",":   100 * time.Millisecond,
+		"...": 800 * time.Millisecond,
+	}
+
+	items := []SynthItem{
+		{Text: "Hello."},
+		{Text: "Really? "},
+		{Text: "Wait..."},
+		{Text: "Then…"},
+		{Text: "First,"},
+		{Text: "No mark"},
+		{Text: "Paragraph end.", PauseAfter: time.Second},
+		{Text: "Short break.", PauseAfter: 100 * time.Millisecond},
+		{Text: "Last."},
+	}
+	applyPunctuationPauses(items, pauses)
+
+	want := []time.Duration{
+		300 * time.Millisecond,
+		500 * time.Millisecond,
+		800 * time.Millisecond,
+		800 * time.Millisecond,
+		100 * time.Millisecond,
+		0,
+		time.Second,
+		300 * time.Millisecond,
+		0,
+	}
+	for i, item := range items {
+		if item.PauseAfter != want[i] {
+			t.Errorf("pause after %q is %v, want %v", item.Text, item.PauseAfter, want[i])
+		}
+	}
+}
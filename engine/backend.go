@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-audio/audio"
+)
+
+// Backend turns one sentence into a WAV file with a model. The caller owns the returned file.
+// When ctx is cancelled the backend should stop and leave no file behind.
+// PiperCLI and WorkerPool run piper; FakeBackend needs neither piper nor real models.
+type Backend interface {
+	Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error)
+}
+
+var (
+	_ Backend = (*PiperCLI)(nil)
+	_ Backend = (*WorkerPool)(nil)
+	_ Backend = (*FakeBackend)(nil)
+)
+
+// Name of the configured backend, kept apart in the sentence cache; empty for piper
+func (e *Engine) backendName() string {
+	if e.config.Backend == nil {
+		return ""
+	}
+	return fmt.Sprintf("%T", e.config.Backend)
+}
+
+// FakeBackend writes a tone, or silence, about as long as reading the text would take.
+// The audio only depends on the text and the length scale, so conversions are deterministic.
+// Models must exist on disk but their contents are never read.
+type FakeBackend struct {
+	// Sample rate of the 16-bit mono output; 0 means 22050 Hz
+	SampleRate int
+	// Frequency of the tone in Hz; 0 writes silence
+	Frequency float64
+	// Audio per character of text, before the length scale; 0 means 60 ms
+	CharDuration time.Duration
+}
+
+func (fb *FakeBackend) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(modelPath); err != nil {
+		return "", fmt.Errorf("model not found: %s", modelPath)
+	}
+
+	sampleRate := fb.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 22050
+	}
+	charDuration := fb.CharDuration
+	if charDuration <= 0 {
+		charDuration = 60 * time.Millisecond
+	}
+	lengthScale := settings.LengthScale
+	if lengthScale <= 0 {
+		lengthScale = 1
+	}
+
+	duration := time.Duration(float64(charDuration) * float64(len([]rune(text))) * lengthScale)
+	frames := int(duration.Seconds() * float64(sampleRate))
+
+	data := make([]int, frames)
+	if fb.Frequency > 0 {
+		for i := range data {
+			data[i] = int(0.3 * 32767 * math.Sin(2*math.Pi*fb.Frequency*float64(i)/float64(sampleRate)))
+		}
+	}
+
+	header := &WAVHeader{SampleRate: uint32(sampleRate), NumChannels: 1, BitsPerSample: 16}
+	buffer := &audio.IntBuffer{
+		Data:           data,
+		Format:         &audio.Format{NumChannels: 1, SampleRate: sampleRate},
+		SourceBitDepth: 16,
+	}
+
+	outputFile := filepath.Join(os.TempDir(), fmt.Sprintf("tts_%s.wav", generateRandomString(8)))
+	if err := writeWAVFile(outputFile, buffer, header); err != nil {
+		os.Remove(outputFile)
+		return "", err
+	}
+	return outputFile, nil
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Wait until the queue has nothing running or queued
+func waitForIdleQueue(t *testing.T, pq *ProcessQueue) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := pq.GetStatus()
+		if status.Running == 0 && status.Queued == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue still has %d running and %d queued tasks", status.Running, status.Queued)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueueDropsCancelledQueuedTask(t *testing.T) {
+	pq := NewProcessQueue(1)
+
+	// Hold the only slot
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go pq.Add(context.Background(), func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := pq.Add(ctx, func(ctx context.Context) (interface{}, error) {
+			ran <- struct{}{}
+			return nil, nil
+		})
+		done <- err
+	}()
+
+	for pq.GetStatus().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Add kept waiting after its context was cancelled")
+	}
+	if status := pq.GetStatus(); status.Queued != 0 || status.Running != 1 {
+		t.Errorf("after dropping: %d queued and %d running, want 0 and 1", status.Queued, status.Running)
+	}
+
+	close(release)
+	waitForIdleQueue(t, pq)
+	select {
+	case <-ran:
+		t.Error("the cancelled task ran once the slot was free")
+	default:
+	}
+}
+
+func TestQueueFreesSlotOfCancelledRunningTask(t *testing.T) {
+	pq := NewProcessQueue(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := pq.Add(ctx, func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		done <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	waitForIdleQueue(t, pq)
+
+	// The slot is free for the next task
+	result, err := pq.Add(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return "next", nil
+	})
+	if err != nil || result != "next" {
+		t.Errorf("got %v, %v after the cancelled task", result, err)
+	}
+}
+
+// Synthesizes like a FakeBackend, except for text with "Block" which waits for its
+// context to be cancelled, the way a killed piper process would end
+type blockingBackend struct {
+	FakeBackend
+	started chan struct{}
+}
+
+func (bb *blockingBackend) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	if strings.Contains(text, "Block") {
+		close(bb.started)
+		<-ctx.Done()
+		return "", &PiperError{Err: errors.New("signal: killed")}
+	}
+	return bb.FakeBackend.Synthesize(ctx, text, modelPath, settings)
+}
+
+func TestCancelledRenderRemovesTempFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	backend := &blockingBackend{started: make(chan struct{})}
+	e := newTestEngineWithConfig(t, Config{Backend: backend, MaxConcurrent: 2})
+
+	// Two of the sentences wait in the queue while the slots are taken
+	conv, err := e.Prepare(ConvertRequest{
+		Text:      "First one is fine. Block on this one. Third one waits. Fourth one waits too.",
+		ModelPath: testModelPath(t, e, "en_US-test"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{}, len(conv.Items))
+	done := make(chan error, 1)
+	go func() {
+		_, err := e.Render(ctx, conv, func(result SentenceResult) {
+			if result.Error == nil {
+				finished <- struct{}{}
+			}
+		})
+		done <- err
+	}()
+
+	// Cancel with one sentence running and the audio of another already written
+	<-backend.started
+	<-finished
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("a cancelled render succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Render kept going after its context was cancelled")
+	}
+
+	waitForIdleQueue(t, e.queue)
+
+	leftovers, _ := filepath.Glob(filepath.Join(tempDir, "*.wav"))
+	if len(leftovers) > 0 {
+		t.Errorf("left behind %v", leftovers)
+	}
+}
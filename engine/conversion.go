@@ -0,0 +1,604 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-audio/audio"
+)
+
+// Options controls how text is read and how the audio is encoded
+type Options struct {
+	// Piper settings: speaker, noise_scale, length_scale, noise_w; also loudness normalization
+	Settings map[string]interface{} `json:"settings"`
+	// "text", "ssml", "markdown", "html" or "dialogue"; SSML is also detected from a leading <speak> tag
+	InputType string `json:"inputType"`
+	// "wav" (default), "flac", "opus" or "mp3"; bitrate in kbps for the lossy ones
+	Format  string `json:"format"`
+	Bitrate int    `json:"bitrate"`
+	// Output sample rate, channels and bit depth; 0 keeps the model's
+	SampleRate    int `json:"sampleRate"`
+	Channels      int `json:"channels"`
+	BitsPerSample int `json:"bitsPerSample"`
+	// Pauses, silence trimming and crossfades between sentences
+	Join *JoinRequest `json:"join"`
+	// Model ID or path by language code ("es", "en"...); the language of each
+	// sentence is detected and read by its voice. modelPath "auto" picks them from the scanned models.
+	VoiceByLanguage map[string]string `json:"voiceByLanguage"`
+}
+
+// ConvertRequest is a text to read with a model. Its JSON form is the body of the server's /convert.
+type ConvertRequest struct {
+	Text      string `json:"text"`
+	ModelPath string `json:"modelPath"`
+	Options
+	// Dialogue input: "[Name] text" scripts use Voices to map names to models,
+	// or the turns can be given directly
+	Voices      map[string]DialogueVoice `json:"voices"`
+	Turns       []DialogueTurn           `json:"turns"`
+	TurnPauseMs *int                     `json:"turnPauseMs"`
+}
+
+// JoinRequest controls how sentences are put together; everything is off by default
+type JoinRequest struct {
+	// Silence in ms after sentences ending in a punctuation mark (".", "?", "!", "…", ...)
+	// and after paragraphs ("paragraph", a blank line in plain text)
+	Pauses          map[string]int `json:"pauses"`
+	TrimSilence     bool           `json:"trimSilence"`
+	TrimThresholdDb *float64       `json:"trimThresholdDb"`
+	CrossfadeMs     int            `json:"crossfadeMs"`
+}
+
+// Silence level below which audio is trimmed, unless the request sets one
+const defaultTrimThresholdDb = -50.0
+
+// SynthItem is one sentence to synthesize, with the silence that follows it
+type SynthItem struct {
+	// Text as piper reads it, after replacements, lexicons and verbalization
+	Text string
+	// The sentence as written, reported in segments and captions
+	SourceText string
+	// Set on the later items of a sentence that was read in several pieces
+	Continued  bool
+	Model      *Model
+	Settings   AudioSettings
+	PauseAfter time.Duration
+	// Detected language when the voice was chosen by language
+	Language string
+}
+
+// Conversion holds a validated request ready for synthesis
+type Conversion struct {
+	Model   *Model
+	Items   []SynthItem
+	Format  string
+	Bitrate int
+	// Requested PCM format of the output; zero fields keep the model's
+	Target WAVHeader
+	// Trimming and crossfades; pauses are kept on the items
+	Join JoinOptions
+	// Loudness normalization of the joined audio; nil leaves levels untouched
+	Loudness *LoudnessOptions
+	// Model ID used for each detected language, when voices were chosen by language
+	Voices map[string]string
+
+	// Bitrate of the request, kept even for formats without one so Stream can refuse it
+	requestedBitrate int
+}
+
+// Validate a convert request, filter its text and split it into sentences.
+// Errors match ErrInvalidRequest, ErrNotFound or ErrNoSentences.
+func (e *Engine) Prepare(req ConvertRequest) (*Conversion, error) {
+	log.Printf("[DEBUG] 📥 Received request - text length: %d, modelPath: %s", len(req.Text), req.ModelPath)
+
+	dialogue := isDialogueRequest(req)
+
+	if req.Text == "" && len(req.Turns) == 0 {
+		return nil, invalidRequest(fmt.Errorf("Text is required"))
+	}
+
+	// Check MAX_TEXT limit if set
+	textLength := len(req.Text)
+	for _, turn := range req.Turns {
+		textLength += len(turn.Text)
+	}
+	if maxTextLength := e.config.MaxTextLength; maxTextLength > 0 && textLength > maxTextLength {
+		return nil, invalidRequest(fmt.Errorf("Text exceeds maximum length of %d characters", maxTextLength))
+	}
+
+	// Dialogues pick their models per turn, so the model path is only a default there
+	if req.ModelPath == "" && !dialogue && len(req.VoiceByLanguage) == 0 {
+		return nil, invalidRequest(fmt.Errorf("Model path is required"))
+	}
+
+	format, bitrate, err := ResolveOutputFormat(req.Format, req.Bitrate)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	target, err := parseTargetFormat(req.SampleRate, req.Channels, req.BitsPerSample)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	join, pauses, err := parseJoinRequest(req.Join)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	loudness, err := parseLoudnessOptions(req.Settings)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	// Voices by language replace the model, which is then only a fallback
+	var selector *VoiceSelector
+	if req.ModelPath == AutoModelPath || len(req.VoiceByLanguage) > 0 {
+		selector, err = e.newVoiceSelector(req.ModelPath, req.VoiceByLanguage)
+		if err != nil {
+			return nil, invalidRequest(err)
+		}
+		log.Printf("[CONVERT] 🌐 Choosing voices by language among: %s", strings.Join(selector.languages(), ", "))
+	}
+
+	// Find model by path
+	var model *Model
+	if req.ModelPath != "" && req.ModelPath != AutoModelPath {
+		model, err = e.FindModelByPath(req.ModelPath)
+		if err != nil {
+			return nil, notFound(fmt.Errorf("Model not found"))
+		}
+		log.Printf("[CONVERT] 🎤 Converting text with model: %s (%s)", model.Name, model.Language)
+	}
+
+	log.Printf("[CONVERT] 📝 Input text length: %d characters", textLength)
+
+	var items []SynthItem
+	if dialogue {
+		items, model, err = e.buildDialogueItems(req, model, selector, pauses["paragraph"])
+	} else {
+		items, err = e.buildTextItems(req.Text, req.InputType, model, selector, parseAudioSettings(req.Settings), pauses["paragraph"])
+	}
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	applyPunctuationPauses(items, pauses)
+
+	if len(items) == 0 {
+		log.Printf("[CONVERT] ❌ Text became empty after processing")
+		return nil, ErrNoSentences
+	}
+
+	var voices map[string]string
+	if selector != nil {
+		voices = make(map[string]string)
+		for _, item := range items {
+			if item.Language != "" {
+				voices[item.Language] = item.Model.ID
+			}
+		}
+		if model == nil {
+			model = items[0].Model
+		}
+	}
+
+	return &Conversion{
+		Model:    model,
+		Items:    items,
+		Format:   format,
+		Bitrate:  bitrate,
+		Target:   target,
+		Join:     join,
+		Loudness: loudness,
+		Voices:   voices,
+
+		requestedBitrate: req.Bitrate,
+	}, nil
+}
+
+// Blank lines separate paragraphs
+var paragraphPattern = regexp.MustCompile(`\n\s*\n`)
+
+// Validate the join settings of a request.
+// Returns the trimming and crossfade options and the pauses by punctuation mark.
+func parseJoinRequest(req *JoinRequest) (JoinOptions, map[string]time.Duration, error) {
+	pauses := map[string]time.Duration{}
+	if req == nil {
+		return JoinOptions{}, pauses, nil
+	}
+
+	for mark, ms := range req.Pauses {
+		if ms < 0 || ms > 10000 {
+			return JoinOptions{}, nil, fmt.Errorf("Pause after %q must be between 0 and 10000 ms", mark)
+		}
+		pauses[mark] = time.Duration(ms) * time.Millisecond
+	}
+
+	if req.CrossfadeMs < 0 || req.CrossfadeMs > 500 {
+		return JoinOptions{}, nil, fmt.Errorf("Crossfade must be between 0 and 500 ms")
+	}
+
+	thresholdDb := defaultTrimThresholdDb
+	if req.TrimThresholdDb != nil {
+		thresholdDb = *req.TrimThresholdDb
+	}
+	if thresholdDb < -90 || thresholdDb > -10 {
+		return JoinOptions{}, nil, fmt.Errorf("Trim threshold must be between -90 and -10 dB")
+	}
+
+	return JoinOptions{
+		TrimSilence:   req.TrimSilence,
+		TrimThreshold: math.Pow(10, thresholdDb/20),
+		Crossfade:     time.Duration(req.CrossfadeMs) * time.Millisecond,
+	}, pauses, nil
+}
+
+// Give every sentence but the last at least the pause configured for its final punctuation mark.
+// Longer pauses already there (paragraphs, SSML breaks, dialogue turns) are kept.
+func applyPunctuationPauses(items []SynthItem, pauses map[string]time.Duration) {
+	if len(pauses) == 0 {
+		return
+	}
+
+	for i := 0; i < len(items)-1; i++ {
+		text := strings.TrimSpace(items[i].Text)
+		if text == "" {
+			continue
+		}
+
+		mark := string([]rune(text)[len([]rune(text))-1])
+		if strings.HasSuffix(text, "...") {
+			mark = "…"
+		}
+
+		pause, ok := pauses[mark]
+		if !ok && mark == "…" {
+			pause = pauses["..."]
+		}
+		if pause > items[i].PauseAfter {
+			items[i].PauseAfter = pause
+		}
+	}
+}
+
+// Validate the requested output sample rate, channels and bit depth
+func parseTargetFormat(sampleRate, channels, bitsPerSample int) (WAVHeader, error) {
+	if sampleRate != 0 && (sampleRate < 8000 || sampleRate > 192000) {
+		return WAVHeader{}, fmt.Errorf("Sample rate must be between 8000 and 192000")
+	}
+	if channels != 0 && channels != 1 && channels != 2 {
+		return WAVHeader{}, fmt.Errorf("Channels must be 1 or 2")
+	}
+	if bitsPerSample != 0 && bitsPerSample != 8 && bitsPerSample != 16 && bitsPerSample != 24 {
+		return WAVHeader{}, fmt.Errorf("Bits per sample must be 8, 16 or 24")
+	}
+
+	return WAVHeader{
+		SampleRate:    uint32(sampleRate),
+		NumChannels:   uint16(channels),
+		BitsPerSample: uint16(bitsPerSample),
+	}, nil
+}
+
+// Turn plain text or SSML into sentences, carrying over SSML breaks as pauses.
+// With a paragraph pause, plain text is split at blank lines and the pause added after each paragraph.
+// Markdown and HTML blocks are always followed by the paragraph pause, or a medium one.
+// With a selector, text read by the default model goes to the voice of its detected language instead.
+func (e *Engine) buildTextItems(text, inputType string, model *Model, selector *VoiceSelector, settings AudioSettings, paragraphPause time.Duration) ([]SynthItem, error) {
+	var segments []SSMLSegment
+	if inputType == "ssml" || (inputType == "" && isSSML(text)) {
+		log.Printf("[CONVERT] 🏷️  Parsing SSML input")
+		var err error
+		segments, err = e.parseSSML(text, model, settings)
+		if err != nil {
+			return nil, err
+		}
+	} else if inputType == "markdown" || inputType == "html" {
+		blockPause := paragraphPause
+		if blockPause == 0 {
+			blockPause = ssmlBreakStrengths["medium"]
+		}
+		if inputType == "html" {
+			log.Printf("[CONVERT] 🌐 Parsing HTML input")
+			var err error
+			segments, err = parseHTMLText(text, model, settings, blockPause)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			log.Printf("[CONVERT] 📝 Parsing markdown input")
+			segments = parseMarkdown(text, model, settings, blockPause)
+		}
+	} else if paragraphPause > 0 {
+		for _, paragraph := range paragraphPattern.Split(text, -1) {
+			segments = append(segments, SSMLSegment{Text: paragraph, Model: model, Settings: settings, Break: paragraphPause})
+		}
+		// No pause after the last paragraph
+		segments[len(segments)-1].Break = 0
+	} else {
+		segments = []SSMLSegment{{Text: text, Model: model, Settings: settings}}
+	}
+
+	items := []SynthItem{}
+	for _, segment := range segments {
+		var segmentItems []SynthItem
+		if selector != nil && segment.Model == model {
+			for _, run := range selector.Split(segment.Text) {
+				runItems := e.buildSynthItems(run.Text, run.Model, segment.Settings)
+				for i := range runItems {
+					runItems[i].Language = run.Language
+				}
+				segmentItems = append(segmentItems, runItems...)
+			}
+		} else {
+			segmentItems = e.buildSynthItems(segment.Text, segment.Model, segment.Settings)
+		}
+
+		if len(segmentItems) == 0 {
+			// Keep the silence of segments that filtered down to nothing
+			if len(items) > 0 {
+				items[len(items)-1].PauseAfter += segment.Break
+			}
+			continue
+		}
+
+		segmentItems[len(segmentItems)-1].PauseAfter += segment.Break
+		items = append(items, segmentItems...)
+	}
+
+	return items, nil
+}
+
+// Split a run of text into sentences and filter each one for a model
+func (e *Engine) buildSynthItems(text string, model *Model, settings AudioSettings) []SynthItem {
+	// Split before replacements, lexicons and verbalization, so every item keeps its sentence as written
+	sentences := splitSourceSentences(processLineBreaks(filterCodeBlocks(text)))
+	log.Printf("[CONVERT] 📄 Split into %d sentences", len(sentences))
+
+	rules := e.lexicons.Rules(model.Language)
+	items := []SynthItem{}
+	for _, sentence := range sentences {
+		processedText := filterTextSegment(sentence, model.Language, model.Replacements, rules)
+
+		// Sentences too long to read at once, or that replacements cut in two, take several items
+		for i, piece := range splitSentences(processedText) {
+			items = append(items, SynthItem{
+				Text:       piece,
+				SourceText: sentence,
+				Continued:  i > 0,
+				Model:      model,
+				Settings:   settings,
+			})
+		}
+	}
+
+	log.Printf("[CONVERT] ✅ %d sentences ready for synthesis", len(items))
+	return items
+}
+
+// Segment locates one sentence in the final audio, in seconds
+type Segment struct {
+	Index int     `json:"index"`
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Model ID and detected language, when voices were chosen by language
+	Voice    string `json:"voice,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// SynthesisResult is the audio file of a conversion and what is known about it
+type SynthesisResult struct {
+	AudioPath string
+	// Where each sentence starts and ends in the audio
+	Segments []Segment
+	// Set when loudness normalization was requested and the audio wasn't silent
+	Loudness *LoudnessReport
+}
+
+// Synthesize every sentence of a conversion and join them into a single file in its output format.
+// The caller owns the returned file and must remove it when done.
+func (e *Engine) Render(ctx context.Context, conv *Conversion, progress func(SentenceResult)) (*SynthesisResult, error) {
+	wavPath, spans, err := e.renderWAV(ctx, conv, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []Segment{}
+	for i, span := range spans {
+		item := conv.Items[i]
+		if item.Continued && len(segments) > 0 {
+			segments[len(segments)-1].End = roundSeconds(span.End)
+			continue
+		}
+
+		segment := Segment{
+			Index: len(segments),
+			Text:  item.SourceText,
+			Start: roundSeconds(span.Start),
+			End:   roundSeconds(span.End),
+		}
+		if item.Language != "" {
+			segment.Voice = item.Model.ID
+			segment.Language = item.Language
+		}
+		segments = append(segments, segment)
+	}
+
+	result := &SynthesisResult{AudioPath: wavPath, Segments: segments}
+
+	if conv.Loudness != nil {
+		log.Printf("[CONVERT] 🔊 Normalizing loudness to %.1f LUFS", conv.Loudness.TargetLUFS)
+		result.Loudness, err = normalizeLoudnessFile(wavPath, *conv.Loudness)
+		if err != nil {
+			log.Printf("[CONVERT] ❌ Error normalizing loudness: %v", err)
+			os.Remove(wavPath)
+			return nil, err
+		}
+	}
+
+	if conv.Format == "" || conv.Format == "wav" {
+		return result, nil
+	}
+
+	log.Printf("[CONVERT] 🗜️  Encoding audio as %s", conv.Format)
+	encodedPath, err := encodeAudio(wavPath, conv.Format, conv.Bitrate)
+	os.Remove(wavPath)
+	if err != nil {
+		log.Printf("[CONVERT] ❌ Error encoding audio: %v", err)
+		return nil, err
+	}
+
+	result.AudioPath = encodedPath
+	return result, nil
+}
+
+func (e *Engine) renderWAV(ctx context.Context, conv *Conversion, progress func(SentenceResult)) (string, []AudioSpan, error) {
+	audioFiles, err := e.generateAudioParallel(ctx, conv.Items, progress)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(audioFiles) == 0 {
+		return "", nil, fmt.Errorf("Failed to generate any audio")
+	}
+
+	// A single sentence is used as is unless it needs silence, trimming or a format change
+	if len(audioFiles) == 1 && conv.Items[0].PauseAfter == 0 && conv.Target == (WAVHeader{}) && !conv.Join.TrimSilence {
+		log.Printf("[CONVERT] 🎵 Using single audio file")
+		duration, err := wavDuration(audioFiles[0])
+		if err != nil {
+			os.Remove(audioFiles[0])
+			return "", nil, err
+		}
+		return audioFiles[0], []AudioSpan{{End: duration}}, nil
+	}
+
+	// Concatenate multiple audio files
+	log.Printf("[CONVERT] 🔗 Concatenating %d audio files", len(audioFiles))
+	concatenatedPath := filepath.Join(os.TempDir(), fmt.Sprintf("final_%s.wav", generateRandomString(8)))
+	options := conv.Join
+	options.Target = &conv.Target
+	options.Pauses = make([]time.Duration, len(conv.Items))
+	for i, item := range conv.Items {
+		options.Pauses[i] = item.PauseAfter
+	}
+	spans, err := concatenateAudio(audioFiles, options, concatenatedPath)
+	if err != nil {
+		log.Printf("[CONVERT] ❌ Error concatenating audio: %v", err)
+		for _, file := range audioFiles {
+			os.Remove(file)
+		}
+		return "", nil, err
+	}
+
+	return concatenatedPath, spans, nil
+}
+
+// Round a duration to whole milliseconds, in seconds
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+// Stream the audio of a conversion in sentence order as soon as each piece is ready.
+// emit is called sequentially with the decoded PCM of each sentence; returning an
+// error from it stops the stream and skips every sentence still in the queue.
+// Streams are PCM, so conversions with another format, a bitrate or loudness
+// normalization (which needs the whole audio) give an error matching ErrInvalidRequest.
+func (e *Engine) Stream(ctx context.Context, conv *Conversion, emit func(index int, buffer *audio.IntBuffer, header *WAVHeader) error) error {
+	if (conv.Format != "" && conv.Format != "wav") || conv.Bitrate != 0 || conv.requestedBitrate != 0 {
+		return invalidRequest(fmt.Errorf("Streams are WAV or PCM; format and bitrate can't be set"))
+	}
+	if conv.Loudness != nil {
+		return invalidRequest(fmt.Errorf("Loudness normalization needs the whole audio and can't be streamed"))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan SentenceResult, len(conv.Items))
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := e.generateAudioParallel(ctx, conv.Items, func(result SentenceResult) {
+			results <- result
+		})
+		done <- err
+	}()
+
+	pending := make(map[int]string)
+	next := 0
+	var streamErr error
+	// The first piece fixes the format of the whole stream, apart from what the request sets
+	var streamHeader *WAVHeader
+
+	// Every sentence reports exactly once, even when it fails or is skipped
+	for received := 0; received < len(conv.Items); received++ {
+		result := <-results
+
+		if result.Error != nil {
+			if streamErr == nil {
+				streamErr = result.Error
+				cancel()
+			}
+			continue
+		}
+
+		if streamErr != nil {
+			os.Remove(result.AudioFile)
+			continue
+		}
+
+		pending[result.Index] = result.AudioFile
+
+		// Flush every piece that is now contiguous with what was already sent
+		for streamErr == nil {
+			audioFile, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+
+			buffer, header, err := ReadWAVFile(audioFile)
+			os.Remove(audioFile)
+			if err == nil {
+				if streamHeader == nil {
+					streamHeader = resolveTargetHeader(header, &conv.Target)
+				}
+				buffer.Data = convertAudioFormat(buffer.Data, header, streamHeader)
+				// Pieces can't overlap in a stream, so crossfades become fades at every join
+				buffer.Data = shapePiece(buffer.Data, streamHeader, conv.Join, next > 0, next < len(conv.Items)-1)
+			}
+			if err == nil {
+				appendSilence(buffer, streamHeader, conv.Items[next].PauseAfter)
+				err = emit(next, buffer, streamHeader)
+			}
+			if err != nil {
+				streamErr = err
+				cancel()
+				break
+			}
+
+			log.Printf("[STREAM] 📤 Sent sentence %d/%d", next+1, len(conv.Items))
+			next++
+		}
+	}
+
+	for _, audioFile := range pending {
+		os.Remove(audioFile)
+	}
+
+	if err := <-done; streamErr == nil && err != nil {
+		streamErr = err
+	}
+
+	return streamErr
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSegmentsReportSourceText(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if _, err := e.Lexicons().Add("en", ReplacementRule{Find: "GoPiper", Replace: "go piper", WholeWord: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	conv, err := e.Prepare(ConvertRequest{
+		Text:      "GoPiper has 2 voices. It costs 3 dollars.",
+		ModelPath: testModelPath(t, e, "en_US-test"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"GoPiper has 2 voices.", "It costs 3 dollars."}
+	if len(conv.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(conv.Items), len(want))
+	}
+	if conv.Items[0].Text == want[0] {
+		t.Errorf("item text %q wasn't respelled", conv.Items[0].Text)
+	}
+
+	result, err := e.Render(context.Background(), conv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(result.AudioPath)
+
+	for i, segment := range result.Segments {
+		if segment.Text != want[i] {
+			t.Errorf("segment %d text %q, want %q", i, segment.Text, want[i])
+		}
+	}
+}
+
+// Rules are applied per sentence, so a find that spans a sentence end never matches
+func TestRulesDontSpanSentences(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if _, err := e.Lexicons().Add("en", ReplacementRule{Find: `one\.\s+Two`, Replace: "one, two", Regex: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{
+		"This is the end of part one. Two dogs ran down the long road.",
+		"This is the end of part one.\n\nTwo dogs ran down the long road.",
+	} {
+		conv, err := e.Prepare(ConvertRequest{Text: text, ModelPath: testModelPath(t, e, "en_US-test")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(conv.Items) != 2 {
+			t.Fatalf("%q gave %d items, want 2", text, len(conv.Items))
+		}
+		for _, item := range conv.Items {
+			if strings.Contains(item.Text, "one, two") {
+				t.Errorf("rule matched across sentences of %q: %q", text, item.Text)
+			}
+		}
+	}
+
+	// A fragment too short to read alone joins the next sentence, where the rule applies
+	conv, err := e.Prepare(ConvertRequest{Text: "Say one.  Two dogs and then stop", ModelPath: testModelPath(t, e, "en_US-test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Items) != 1 || !strings.Contains(conv.Items[0].Text, "one, two") {
+		t.Errorf("got %+v, want one item with the rule applied", conv.Items)
+	}
+}
+
+func TestLongSentenceIsOneSegment(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	// Over 400 characters without a sentence end, so piper reads it in pieces split at "cuando"
+	long := strings.Repeat("esta frase sigue y sigue, cuando nadie la detiene ", 10) + "hasta aquí."
+	question := "¿Se lee esta parte del texto tal como está escrita?"
+
+	conv, err := e.Prepare(ConvertRequest{Text: long + " " + question, ModelPath: testModelPath(t, e, "es_MX-test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Items) < 3 {
+		t.Fatalf("got %d items, want the long sentence in pieces", len(conv.Items))
+	}
+
+	result, err := e.Render(context.Background(), conv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(result.AudioPath)
+
+	want := []string{strings.TrimSpace(long), question}
+	if len(result.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(result.Segments), len(want))
+	}
+	for i, segment := range result.Segments {
+		if segment.Index != i || segment.Text != want[i] {
+			t.Errorf("segment %d is #%d %.60q, want %.60q", i, segment.Index, segment.Text, want[i])
+		}
+	}
+	if result.Segments[0].End > result.Segments[1].Start {
+		t.Errorf("first segment ends at %.2f, after the second starts at %.2f", result.Segments[0].End, result.Segments[1].Start)
+	}
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Silence between two turns of a dialogue unless the request sets turnPauseMs
+const defaultTurnPause = 400 * time.Millisecond
+
+// A voice used in a dialogue script: a model ID or path, with its own speaker and settings
+type DialogueVoice struct {
+	Voice    string                 `json:"voice"`
+	Speaker  *int                   `json:"speaker"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// One turn of a dialogue. Voice is a name from the request's voices map,
+// a model ID or a model path; empty means the request's modelPath.
+type DialogueTurn struct {
+	Voice    string                 `json:"voice"`
+	Text     string                 `json:"text"`
+	Speaker  *int                   `json:"speaker"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// Matches the "[Name]" that starts each turn of a script
+var dialogueTagPattern = regexp.MustCompile(`\[([^\[\]\n]+)\]`)
+
+// Check whether a request should be read as a dialogue
+func isDialogueRequest(req ConvertRequest) bool {
+	if req.InputType == "dialogue" || len(req.Turns) > 0 {
+		return true
+	}
+	// A script is only recognized without inputType when voices are given, so
+	// ordinary text starting with brackets is left alone
+	return req.InputType == "" && len(req.Voices) > 0 && strings.HasPrefix(strings.TrimSpace(req.Text), "[")
+}
+
+// Split a "[Ana] Hola... [Bob] Hi..." script into turns.
+// Text before the first tag is spoken by the default voice.
+func parseDialogueScript(script string) []DialogueTurn {
+	turns := []DialogueTurn{}
+	matches := dialogueTagPattern.FindAllStringSubmatchIndex(script, -1)
+
+	leading := script
+	if len(matches) > 0 {
+		leading = script[:matches[0][0]]
+	}
+	if text := strings.TrimSpace(leading); text != "" {
+		turns = append(turns, DialogueTurn{Text: text})
+	}
+
+	for i, match := range matches {
+		end := len(script)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		text := strings.TrimSpace(script[match[1]:end])
+		if text == "" {
+			continue
+		}
+		turns = append(turns, DialogueTurn{
+			Voice: strings.TrimSpace(script[match[2]:match[3]]),
+			Text:  text,
+		})
+	}
+
+	return turns
+}
+
+// Build the sentences of a dialogue, each turn with its own model and settings.
+// Turns without a voice are read by language when there is a selector.
+// Returns the model of the first turn when the request has no default model.
+func (e *Engine) buildDialogueItems(req ConvertRequest, defaultModel *Model, selector *VoiceSelector, paragraphPause time.Duration) ([]SynthItem, *Model, error) {
+	turns := req.Turns
+	if len(turns) == 0 {
+		turns = parseDialogueScript(req.Text)
+	}
+
+	turnPause := defaultTurnPause
+	if req.TurnPauseMs != nil {
+		if *req.TurnPauseMs < 0 || *req.TurnPauseMs > 10000 {
+			return nil, nil, fmt.Errorf("Pause between turns (turnPauseMs) must be between 0 and 10000 ms")
+		}
+		turnPause = time.Duration(*req.TurnPauseMs) * time.Millisecond
+	}
+
+	log.Printf("[DIALOGUE] 🎭 Building dialogue with %d turns", len(turns))
+
+	items := []SynthItem{}
+	mainModel := defaultModel
+	for i, turn := range turns {
+		model, settings, err := e.resolveDialogueVoice(req, turn, defaultModel, selector != nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("turn %d: %v", i+1, err)
+		}
+
+		turnSelector := selector
+		if model != defaultModel {
+			turnSelector = nil
+		}
+
+		turnItems, err := e.buildTextItems(turn.Text, "", model, turnSelector, settings, paragraphPause)
+		if err != nil {
+			return nil, nil, fmt.Errorf("turn %d: %v", i+1, err)
+		}
+		if len(turnItems) == 0 {
+			continue
+		}
+		if mainModel == nil {
+			mainModel = turnItems[0].Model
+		}
+
+		if len(items) > 0 {
+			items[len(items)-1].PauseAfter += turnPause
+		}
+		items = append(items, turnItems...)
+	}
+
+	return items, mainModel, nil
+}
+
+// Find the model and settings of a turn. Settings are layered: request,
+// then voice, then turn, so each level only needs to set what differs.
+// With byLanguage, a turn without a voice may have no model; it is picked later by language.
+func (e *Engine) resolveDialogueVoice(req ConvertRequest, turn DialogueTurn, defaultModel *Model, byLanguage bool) (*Model, AudioSettings, error) {
+	voice, named := req.Voices[turn.Voice]
+	reference := turn.Voice
+	if named && voice.Voice != "" {
+		reference = voice.Voice
+	}
+
+	var model *Model
+	switch {
+	case reference == "":
+		if defaultModel == nil && !byLanguage {
+			return nil, AudioSettings{}, fmt.Errorf("no voice given and no modelPath to fall back to")
+		}
+		model = defaultModel
+	default:
+		var err error
+		model, err = e.FindModelByPath(reference)
+		if err != nil {
+			model, err = e.FindModelByID(reference)
+		}
+		if err != nil {
+			return nil, AudioSettings{}, fmt.Errorf("dialogue voice not found: %s", reference)
+		}
+	}
+
+	merged := map[string]interface{}{}
+	for _, layer := range []map[string]interface{}{req.Settings, voice.Settings, turn.Settings} {
+		for key, value := range layer {
+			merged[key] = value
+		}
+	}
+	settings := parseAudioSettings(merged)
+
+	if voice.Speaker != nil {
+		settings.Speaker = *voice.Speaker
+	}
+	if turn.Speaker != nil {
+		settings.Speaker = *turn.Speaker
+	}
+
+	return model, settings, nil
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDialogueScript(t *testing.T) {
+	turns := parseDialogueScript("Intro line. [Ana] Hola, ¿qué tal? [Bob] Fine, thanks.\n[Ana] Adiós.")
+	want := []DialogueTurn{
+		{Text: "Intro line."},
+		{Voice: "Ana", Text: "Hola, ¿qué tal?"},
+		{Voice: "Bob", Text: "Fine, thanks."},
+		{Voice: "Ana", Text: "Adiós."},
+	}
+	if !reflect.DeepEqual(turns, want) {
+		t.Errorf("got %+v, want %+v", turns, want)
+	}
+}
+
+func TestDialogueTurnPause(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	request := func(pauseMs int) ConvertRequest {
+		return ConvertRequest{
+			Turns: []DialogueTurn{
+				{Voice: "en_US-test", Text: "Hello there."},
+				{Voice: "es_MX-test", Text: "Hola."},
+			},
+			TurnPauseMs: &pauseMs,
+		}
+	}
+
+	conv, err := e.Prepare(request(750))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Items) != 2 || conv.Items[0].PauseAfter != 750*time.Millisecond {
+		t.Errorf("got %d items, pause after the first turn %v; want 2, 750ms", len(conv.Items), conv.Items[0].PauseAfter)
+	}
+
+	for _, pauseMs := range []int{-1, 10001, 2000000000} {
+		if _, err := e.Prepare(request(pauseMs)); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("turnPauseMs %d: got %v, want ErrInvalidRequest", pauseMs, err)
+		}
+	}
+}
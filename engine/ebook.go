@@ -0,0 +1,547 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Largest file read from an EPUB, and most data read from all its files together,
+// to keep a broken or hostile archive from filling memory
+const (
+	maxEPUBEntrySize = 32 << 20
+	maxEPUBTotalSize = 128 << 20
+)
+
+// Elements that start a new block of text
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"caption": true, "dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// Blocks read as items of a list, with short pauses between them
+var htmlItemElements = map[string]bool{"li": true, "dt": true, "dd": true, "tr": true}
+
+// Elements whose content is never read
+var htmlSkippedElements = map[string]bool{
+	"button": true, "head": true, "iframe": true, "math": true, "nav": true, "noscript": true,
+	"object": true, "rp": true, "rt": true, "script": true, "select": true, "style": true,
+	"svg": true, "template": true,
+}
+
+// A block of text of an HTML document
+type htmlBlock struct {
+	Text string
+	// 1-6 for headings
+	Heading int
+	// List item, definition or table row
+	Item bool
+}
+
+// A chapter of a book, as the blocks of its text
+type bookChapter struct {
+	Title  string
+	Blocks []htmlBlock
+}
+
+// Extract the text of an HTML or XHTML document as blocks, together with its <title>.
+// The document doesn't need to be well-formed; reading stops at the first error it can't recover from.
+func extractHTMLBlocks(input []byte) ([]htmlBlock, string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	// Declared charsets are trusted to be UTF-8 compatible
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	blocks := []htmlBlock{}
+	var text, title strings.Builder
+	// Open block elements, innermost last
+	stack := []string{}
+	skipDepth := 0
+	inTitle, titleDone := false, false
+
+	flush := func() {
+		content := strings.Join(strings.Fields(text.String()), " ")
+		text.Reset()
+		if content == "" {
+			return
+		}
+
+		block := htmlBlock{Text: content}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if len(stack[i]) == 2 && stack[i][0] == 'h' && stack[i][1] >= '1' && stack[i][1] <= '6' {
+				block.Heading = int(stack[i][1] - '0')
+				break
+			}
+			if htmlItemElements[stack[i]] {
+				block.Item = true
+				break
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(blocks) == 0 && strings.TrimSpace(text.String()) == "" {
+				return nil, "", fmt.Errorf("invalid HTML: %v", err)
+			}
+			log.Printf("[EBOOK] ⚠️  Stopped reading HTML early: %v", err)
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "title" && !titleDone && !inTitle:
+				inTitle = true
+			case skipDepth > 0 || htmlSkippedElements[name]:
+				skipDepth++
+			case htmlBlockElements[name]:
+				flush()
+				stack = append(stack, name)
+			case name == "td" || name == "th":
+				if strings.TrimSpace(text.String()) != "" {
+					text.WriteString(", ")
+				}
+			case name == "br":
+				text.WriteString(" ")
+			}
+
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "title" && inTitle:
+				inTitle, titleDone = false, true
+			case skipDepth > 0:
+				skipDepth--
+			case htmlBlockElements[name]:
+				flush()
+				// HTML may leave inner elements open, so pop up to the matching one
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == name {
+						stack = stack[:i]
+						break
+					}
+				}
+			}
+
+		case xml.CharData:
+			if inTitle {
+				title.Write(t)
+			} else if skipDepth == 0 {
+				text.Write(t)
+			}
+		}
+	}
+	flush()
+
+	return blocks, strings.Join(strings.Fields(title.String()), " "), nil
+}
+
+// Turn HTML into segments to read, with the same pauses as markdown:
+// blockPause after headings and paragraphs, a weak pause between list items and table rows
+func parseHTMLText(input string, model *Model, settings AudioSettings, blockPause time.Duration) ([]SSMLSegment, error) {
+	blocks, _, err := extractHTMLBlocks([]byte(input))
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []SSMLSegment{}
+	for i, block := range blocks {
+		pause := blockPause
+		if block.Item && i+1 < len(blocks) && blocks[i+1].Item {
+			pause = ssmlBreakStrengths["weak"]
+		}
+
+		text := block.Text
+		if block.Heading > 0 || block.Item {
+			text = markdownSentence(text)
+		}
+		segments = append(segments, SSMLSegment{Text: text, Model: model, Settings: settings, Break: pause})
+	}
+
+	if len(segments) > 0 {
+		segments[len(segments)-1].Break = 0
+	}
+
+	log.Printf("[EBOOK] Parsed %d HTML blocks", len(segments))
+	return segments, nil
+}
+
+// Write blocks back as minimal HTML, to be read with inputType "html"
+func blocksToHTML(blocks []htmlBlock) string {
+	var out strings.Builder
+	for _, block := range blocks {
+		text := html.EscapeString(block.Text)
+		switch {
+		case block.Heading > 0:
+			fmt.Fprintf(&out, "<h%d>%s</h%d>\n", block.Heading, text, block.Heading)
+		case block.Item:
+			fmt.Fprintf(&out, "<li>%s</li>\n", text)
+		default:
+			fmt.Fprintf(&out, "<p>%s</p>\n", text)
+		}
+	}
+	return out.String()
+}
+
+// Split an HTML page into chapters at its top-level headings: <h1> when there are
+// several, otherwise <h2>. Headings before the first chapter with text are kept with it.
+func readHTMLBook(input []byte) (string, []bookChapter, error) {
+	blocks, title, err := extractHTMLBlocks(input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	counts := make(map[int]int)
+	for _, block := range blocks {
+		counts[block.Heading]++
+	}
+	level := 0
+	if counts[1] > 1 {
+		level = 1
+	} else if counts[2] > 0 {
+		level = 2
+	}
+
+	chapters := []bookChapter{}
+	current := bookChapter{}
+	hasText := false
+	for _, block := range blocks {
+		if level > 0 && block.Heading == level {
+			if hasText {
+				chapters = append(chapters, current)
+				current, hasText = bookChapter{}, false
+			}
+			current.Title = block.Text
+		}
+		if block.Heading == 0 {
+			hasText = true
+		}
+		current.Blocks = append(current.Blocks, block)
+	}
+	if len(current.Blocks) > 0 {
+		chapters = append(chapters, current)
+	}
+
+	if title == "" && counts[1] == 1 {
+		for _, block := range blocks {
+			if block.Heading == 1 {
+				title = block.Text
+				break
+			}
+		}
+	}
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = title
+		}
+	}
+
+	return title, chapters, nil
+}
+
+// BookChapter is a chapter of a book, its text written as HTML to be read with inputType "html"
+type BookChapter struct {
+	Title string
+	Text  string
+}
+
+// Read the chapters of a book: an EPUB, recognized by its zip signature, or else an HTML page
+func ReadBook(data []byte) (string, []BookChapter, error) {
+	var title string
+	var chapters []bookChapter
+	var err error
+	if len(data) >= 4 && string(data[:4]) == "PK\x03\x04" {
+		title, chapters, err = readEPUB(data)
+	} else {
+		title, chapters, err = readHTMLBook(data)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	bookChapters := make([]BookChapter, len(chapters))
+	for i, chapter := range chapters {
+		bookChapters[i] = BookChapter{Title: chapter.Title, Text: blocksToHTML(chapter.Blocks)}
+	}
+	return title, bookChapters, nil
+}
+
+// Layouts of the EPUB files that are read
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Titles   []string `xml:"metadata>title"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type ncxDocument struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+// Read the chapters of an EPUB (2 or 3) in spine order. Chapter titles come from the
+// navigation document (nav or NCX), falling back to the first heading of the chapter.
+func readEPUB(data []byte) (string, []bookChapter, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid EPUB: %v", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	errTooLarge := fmt.Errorf("Invalid EPUB: more than %d MB once decompressed", maxEPUBTotalSize>>20)
+	var totalSize int64
+	readFile := func(name string) ([]byte, error) {
+		file, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing", name)
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		limit := int64(maxEPUBEntrySize)
+		if remaining := maxEPUBTotalSize - totalSize; remaining < limit {
+			limit = remaining
+		}
+		content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+		totalSize += int64(len(content))
+		if err != nil {
+			return nil, err
+		}
+		if totalSize > maxEPUBTotalSize {
+			return nil, errTooLarge
+		}
+		if len(content) > maxEPUBEntrySize {
+			return nil, fmt.Errorf("%s is too large", name)
+		}
+		return content, nil
+	}
+
+	var container epubContainer
+	content, err := readFile("META-INF/container.xml")
+	if err == nil {
+		err = xml.Unmarshal(content, &container)
+	}
+	if err != nil || len(container.Rootfiles) == 0 {
+		return "", nil, fmt.Errorf("Invalid EPUB: no package document found")
+	}
+	packagePath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	content, err = readFile(packagePath)
+	if err == nil {
+		err = xml.Unmarshal(content, &pkg)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid EPUB package document: %v", err)
+	}
+
+	title := ""
+	if len(pkg.Titles) > 0 {
+		title = strings.TrimSpace(pkg.Titles[0])
+	}
+
+	// Chapter titles by file, from the EPUB 3 nav document or else the EPUB 2 NCX
+	titles := make(map[string]string)
+	for _, item := range pkg.Manifest {
+		isNav := strings.Contains(" "+item.Properties+" ", " nav ")
+		if !isNav && (item.ID != pkg.Spine.Toc || len(titles) > 0) {
+			continue
+		}
+		navPath := resolveEPUBPath(packagePath, item.Href)
+		content, err := readFile(navPath)
+		if err == errTooLarge {
+			return "", nil, err
+		}
+		if err != nil {
+			log.Printf("[EBOOK] ⚠️  Could not read navigation %s: %v", navPath, err)
+			continue
+		}
+		if isNav {
+			titles = readEPUBNav(content, navPath)
+			break
+		}
+		titles = readEPUBNCX(content, navPath)
+	}
+
+	manifest := make(map[string]int)
+	for i, item := range pkg.Manifest {
+		manifest[item.ID] = i
+	}
+
+	chapters := []bookChapter{}
+	for _, itemRef := range pkg.Spine.ItemRefs {
+		index, ok := manifest[itemRef.IDRef]
+		if !ok || itemRef.Linear == "no" {
+			continue
+		}
+		item := pkg.Manifest[index]
+		if strings.Contains(" "+item.Properties+" ", " nav ") || !strings.Contains(item.MediaType, "html") {
+			continue
+		}
+
+		chapterPath := resolveEPUBPath(packagePath, item.Href)
+		content, err := readFile(chapterPath)
+		if err == errTooLarge {
+			return "", nil, err
+		}
+		if err != nil {
+			log.Printf("[EBOOK] ⚠️  Skipping %s: %v", chapterPath, err)
+			continue
+		}
+		blocks, _, err := extractHTMLBlocks(content)
+		if err != nil || len(blocks) == 0 {
+			// Cover images and blank pages have nothing to read
+			continue
+		}
+
+		chapter := bookChapter{Title: titles[chapterPath], Blocks: blocks}
+		if chapter.Title == "" {
+			for _, block := range blocks {
+				if block.Heading > 0 {
+					chapter.Title = block.Text
+					break
+				}
+			}
+		}
+		if chapter.Title == "" {
+			chapter.Title = fmt.Sprintf("Chapter %d", len(chapters)+1)
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	log.Printf("[EBOOK] 📖 Read EPUB '%s' with %d chapters", title, len(chapters))
+	return title, chapters, nil
+}
+
+// Resolve an href relative to the file it appears in, dropping the fragment
+func resolveEPUBPath(base, href string) string {
+	if i := strings.Index(href, "#"); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(path.Dir(base), href)
+}
+
+// Titles of the table of contents of an EPUB 3 nav document, by file
+func readEPUBNav(content []byte, navPath string) map[string]string {
+	titles := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	// Depth inside the toc nav, 0 outside of it
+	navDepth := 0
+	href := ""
+	var label strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if navDepth > 0 {
+				navDepth++
+			} else if t.Name.Local == "nav" && strings.Contains(ssmlAttr(t, "type"), "toc") {
+				navDepth = 1
+			}
+			if navDepth > 0 && t.Name.Local == "a" {
+				href = ssmlAttr(t, "href")
+				label.Reset()
+			}
+		case xml.EndElement:
+			if navDepth > 0 && t.Name.Local == "a" && href != "" {
+				file := resolveEPUBPath(navPath, href)
+				if _, ok := titles[file]; !ok {
+					titles[file] = strings.Join(strings.Fields(label.String()), " ")
+				}
+				href = ""
+			}
+			if navDepth > 0 {
+				navDepth--
+			}
+		case xml.CharData:
+			if href != "" {
+				label.Write(t)
+			}
+		}
+	}
+	return titles
+}
+
+// Titles of an EPUB 2 NCX, by file
+func readEPUBNCX(content []byte, ncxPath string) map[string]string {
+	titles := make(map[string]string)
+	var ncx ncxDocument
+	if err := xml.Unmarshal(content, &ncx); err != nil {
+		log.Printf("[EBOOK] ⚠️  Could not read NCX: %v", err)
+		return titles
+	}
+
+	var walk func(points []ncxNavPoint)
+	walk = func(points []ncxNavPoint) {
+		for _, point := range points {
+			file := resolveEPUBPath(ncxPath, point.Content.Src)
+			if _, ok := titles[file]; !ok {
+				titles[file] = strings.Join(strings.Fields(point.Label), " ")
+			}
+			walk(point.Children)
+		}
+	}
+	walk(ncx.NavPoints)
+	return titles
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// A file of a test EPUB
+type epubTestFile struct {
+	name    string
+	content string
+	// Filled with this many bytes of padding inside an HTML comment, if set
+	padding int
+}
+
+// Zip files into an EPUB, in order
+func buildTestEPUB(t *testing.T, files []epubTestFile) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(writer, file.content); err != nil {
+			t.Fatal(err)
+		}
+		if file.padding > 0 {
+			if _, err := io.WriteString(writer, "<!--"+strings.Repeat(" ", file.padding)+"-->"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const epubContainerXML = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+func epubChapter(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>Ignored</title></head><body>` + body + `</body></html>`
+}
+
+func TestReadEPUB3(t *testing.T) {
+	data := buildTestEPUB(t, []epubTestFile{
+		{name: "mimetype", content: "application/epub+zip"},
+		{name: "META-INF/container.xml", content: epubContainerXML},
+		{name: "OEBPS/content.opf", content: `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title> The Test Book </dc:title></metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ch2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="notes" href="notes.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine>
+    <itemref idref="cover"/>
+    <itemref idref="nav"/>
+    <itemref idref="ch1"/>
+    <itemref idref="css"/>
+    <itemref idref="ch2"/>
+    <itemref idref="notes" linear="no"/>
+    <itemref idref="missing"/>
+  </spine>
+</package>`},
+		{name: "OEBPS/nav.xhtml", content: epubChapter(`<nav epub:type="landmarks"><ol><li><a href="cover.xhtml">Cover</a></li></ol></nav>
+<nav epub:type="toc"><ol>
+  <li><a href="text/ch1.xhtml">The   Beginning</a></li>
+  <li><a href="text/chapter%202.xhtml#start">The End</a></li>
+</ol></nav>`)},
+		{name: "OEBPS/cover.xhtml", content: epubChapter(`<img src="cover.jpg"/>`)},
+		{name: "OEBPS/text/ch1.xhtml", content: epubChapter(`<h1>One</h1><p>It was a dark night.</p><ul><li>First</li><li>Second</li></ul>`)},
+		{name: "OEBPS/text/chapter 2.xhtml", content: epubChapter(`<h1 id="start">Two</h1><p>And then it ended.</p>`)},
+		{name: "OEBPS/notes.xhtml", content: epubChapter(`<p>Footnotes are not read.</p>`)},
+		{name: "OEBPS/style.css", content: `p { margin: 0 }`},
+	})
+
+	title, chapters, err := ReadBook(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "The Test Book" {
+		t.Errorf("got title %q", title)
+	}
+
+	want := []BookChapter{
+		{Title: "The Beginning", Text: "<h1>One</h1>\n<p>It was a dark night.</p>\n<li>First</li>\n<li>Second</li>\n"},
+		{Title: "The End", Text: "<h1>Two</h1>\n<p>And then it ended.</p>\n"},
+	}
+	if len(chapters) != len(want) {
+		t.Fatalf("got %d chapters %+v, want %d", len(chapters), chapters, len(want))
+	}
+	for i := range want {
+		if chapters[i] != want[i] {
+			t.Errorf("chapter %d is %+v, want %+v", i, chapters[i], want[i])
+		}
+	}
+}
+
+func TestReadEPUB2(t *testing.T) {
+	data := buildTestEPUB(t, []epubTestFile{
+		{name: "mimetype", content: "application/epub+zip"},
+		{name: "META-INF/container.xml", content: epubContainerXML},
+		{name: "OEBPS/content.opf", content: `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Old Book</dc:title></metadata>
+  <manifest>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="a" href="a.html" media-type="text/html"/>
+    <item id="b" href="b.html" media-type="text/html"/>
+    <item id="c" href="c.html" media-type="text/html"/>
+  </manifest>
+  <spine toc="ncx"><itemref idref="a"/><itemref idref="b"/><itemref idref="c"/></spine>
+</package>`},
+		{name: "OEBPS/toc.ncx", content: `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><navMap>
+  <navPoint id="p1"><navLabel><text>Prologue</text></navLabel><content src="a.html"/>
+    <navPoint id="p1.1"><navLabel><text>A section</text></navLabel><content src="a.html#s1"/></navPoint>
+  </navPoint>
+</navMap></ncx>`},
+		{name: "OEBPS/a.html", content: `<html><body><p>From the NCX.<br>Still here.</p></body></html>`},
+		{name: "OEBPS/b.html", content: `<html><body><h2>Heading Title</h2><p>From the heading.</p></body></html>`},
+		{name: "OEBPS/c.html", content: `<html><body><p>Numbered.</p></body></html>`},
+	})
+
+	title, chapters, err := ReadBook(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Old Book" {
+		t.Errorf("got title %q", title)
+	}
+
+	want := []string{"Prologue", "Heading Title", "Chapter 3"}
+	if len(chapters) != len(want) {
+		t.Fatalf("got %d chapters, want %d", len(chapters), len(want))
+	}
+	for i, chapter := range chapters {
+		if chapter.Title != want[i] {
+			t.Errorf("chapter %d is titled %q, want %q", i, chapter.Title, want[i])
+		}
+	}
+	if chapters[0].Text != "<p>From the NCX. Still here.</p>\n" {
+		t.Errorf("got first chapter %q", chapters[0].Text)
+	}
+}
+
+func TestReadEPUBErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []epubTestFile
+		want  string
+	}{
+		{
+			name:  "no container",
+			files: []epubTestFile{{name: "mimetype", content: "application/epub+zip"}},
+			want:  "no package document found",
+		},
+		{
+			name:  "missing package",
+			files: []epubTestFile{{name: "META-INF/container.xml", content: epubContainerXML}},
+			want:  "Invalid EPUB package document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadBook(buildTestEPUB(t, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEPUBTooLarge(t *testing.T) {
+	// Chapters that each fit, but together decompress to more than the total limit
+	const chapterCount = maxEPUBTotalSize/(maxEPUBEntrySize-1024) + 1
+	large := []epubTestFile{
+		{name: "META-INF/container.xml", content: epubContainerXML},
+	}
+	manifest, spine := "", ""
+	for i := 0; i < chapterCount; i++ {
+		name := string(rune('a'+i)) + ".html"
+		manifest += `<item id="` + name + `" href="` + name + `" media-type="text/html"/>`
+		spine += `<itemref idref="` + name + `"/>`
+		large = append(large, epubTestFile{name: "OEBPS/" + name, content: "<p>Text.</p>", padding: maxEPUBEntrySize - 1024})
+	}
+	large = append(large, epubTestFile{name: "OEBPS/content.opf", content: `<package><manifest>` + manifest + `</manifest><spine>` + spine + `</spine></package>`})
+
+	_, _, err := ReadBook(buildTestEPUB(t, large))
+	if err == nil || !strings.Contains(err.Error(), "once decompressed") {
+		t.Errorf("got %v, want the book rejected as too large", err)
+	}
+
+}
@@ -0,0 +1,209 @@
+// Package engine turns text into speech with piper. It scans voice models, filters
+// and splits text into sentences, queues them for piper and joins the audio.
+//
+// Every Engine has its own models, queue, piper workers, sentence cache and lexicons,
+// so several can run side by side with different settings.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is everything an Engine needs to know; nothing is read from the environment
+type Config struct {
+	// Synthesizes every sentence; nil runs piper, through persistent workers when enabled
+	Backend Backend
+	// Path of the piper executable, required unless Backend is set
+	PiperPath string
+	// Directory with piper's shared libraries, put first in LD_LIBRARY_PATH. Empty leaves the environment alone.
+	LibraryDir string
+	// Directories scanned for .onnx models (with their .onnx.json) and lexicons
+	ModelPaths []string
+	// Piper processes running at once; 0 means two per CPU core
+	MaxConcurrent int
+	// Keep piper processes alive between sentences, with their model loaded
+	PersistentWorkers bool
+	// Stop persistent workers idle for this long; 0 means 5 minutes
+	WorkerIdleTimeout time.Duration
+	// On-disk cache of synthesized sentences; off when CacheDir is empty or CacheMaxBytes is 0
+	CacheDir      string
+	CacheMaxBytes int64
+	// Longest text a conversion accepts, in bytes; 0 means no limit
+	MaxTextLength int
+	// Longest a single sentence may take before piper is killed; 0 means 2 minutes
+	SentenceTimeout time.Duration
+	// Times a sentence is tried again after piper crashes, hangs or writes nothing;
+	// 0 means 2, a negative number never retries
+	SentenceRetries int
+}
+
+// Synthesizer turns text into audio. voice is a model ID or .onnx path, or "auto" to
+// choose one per sentence by language. The stream is encoded in opts.Format (WAV by default)
+// and must be closed by the caller.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text, voice string, opts Options) (io.ReadCloser, error)
+}
+
+// Engine is a Synthesizer backed by piper, or by the Backend of its config
+type Engine struct {
+	config   Config
+	cli      *PiperCLI
+	queue    *ProcessQueue
+	workers  *WorkerPool
+	cache    *SentenceCache
+	lexicons *LexiconStore
+
+	persistentWorkers atomic.Bool
+
+	// Guards models and modelPaths
+	mu         sync.RWMutex
+	models     []Model
+	modelPaths []string
+}
+
+var _ Synthesizer = (*Engine)(nil)
+
+// Create an engine and scan its model paths
+func New(config Config) (*Engine, error) {
+	if config.PiperPath == "" && config.Backend == nil {
+		return nil, fmt.Errorf("piper path is required")
+	}
+
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = runtime.NumCPU() * 2
+	}
+	if config.WorkerIdleTimeout <= 0 {
+		config.WorkerIdleTimeout = 5 * time.Minute
+	}
+	if config.SentenceTimeout <= 0 {
+		config.SentenceTimeout = 2 * time.Minute
+	}
+	if config.SentenceRetries == 0 {
+		config.SentenceRetries = 2
+	} else if config.SentenceRetries < 0 {
+		config.SentenceRetries = 0
+	}
+
+	e := &Engine{
+		config:     config,
+		cli:        &PiperCLI{Path: config.PiperPath, LibraryDir: config.LibraryDir},
+		queue:      NewProcessQueue(config.MaxConcurrent),
+		lexicons:   NewLexiconStore(),
+		modelPaths: append([]string{}, config.ModelPaths...),
+	}
+	e.persistentWorkers.Store(config.PersistentWorkers)
+	e.workers = NewWorkerPool(func(args ...string) *exec.Cmd { return e.cli.Command(context.Background(), args...) }, func() int { return e.queue.GetStatus().MaxConcurrent }, config.WorkerIdleTimeout)
+
+	if config.CacheDir != "" && config.CacheMaxBytes > 0 {
+		cache, err := NewSentenceCache(config.CacheDir, config.CacheMaxBytes)
+		if err != nil {
+			log.Printf("[CACHE] ⚠️  Sentence cache disabled: %v", err)
+		} else {
+			e.cache = cache
+		}
+	} else {
+		log.Printf("[CACHE] Sentence cache disabled")
+	}
+
+	if err := e.Rescan(); err != nil {
+		log.Printf("[SCAN] Warning: %v", err)
+	}
+
+	return e, nil
+}
+
+// Stop every piper worker. Conversions still running fall back to starting piper per sentence.
+func (e *Engine) Close() {
+	e.workers.Shutdown()
+}
+
+// Change how many piper processes run at once (1-32)
+func (e *Engine) SetMaxConcurrent(max int) {
+	e.queue.SetMaxConcurrent(max)
+}
+
+// Turn persistent piper workers on or off; turning them off stops the idle ones
+func (e *Engine) SetPersistentWorkers(enabled bool) {
+	e.persistentWorkers.Store(enabled)
+	if !enabled {
+		e.workers.Close()
+	}
+}
+
+func (e *Engine) PersistentWorkers() bool {
+	return e.persistentWorkers.Load()
+}
+
+// State of the sentence queue, with the cache counters
+func (e *Engine) QueueStatus() QueueStatus {
+	status := e.queue.GetStatus()
+	if e.cache != nil {
+		cacheStats := e.cache.Stats()
+		status.CacheHits = cacheStats.Hits
+		status.CacheMisses = cacheStats.Misses
+		status.Cache = &cacheStats
+	}
+	return status
+}
+
+// Number of running piper workers and how many are synthesizing right now
+func (e *Engine) WorkerStats() (total, busy int) {
+	return e.workers.Stats()
+}
+
+// Shared pronunciation lexicons, loaded from the model paths
+func (e *Engine) Lexicons() *LexiconStore {
+	return e.lexicons
+}
+
+// Synthesize text into a single audio file and stream it. The file is removed on Close.
+func (e *Engine) Synthesize(ctx context.Context, text, voice string, opts Options) (io.ReadCloser, error) {
+	modelPath := voice
+	if voice != AutoModelPath {
+		model, err := e.FindModelByPath(voice)
+		if err != nil {
+			model, err = e.FindModelByID(voice)
+		}
+		if err != nil {
+			return nil, notFound(fmt.Errorf("Voice not found: %s", voice))
+		}
+		modelPath = model.OnnxPath
+	}
+
+	conv, err := e.Prepare(ConvertRequest{Text: text, ModelPath: modelPath, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.Render(ctx, conv, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(result.AudioPath)
+	if err != nil {
+		os.Remove(result.AudioPath)
+		return nil, err
+	}
+	return &tempFileReader{File: file}, nil
+}
+
+// A temporary file that is removed once read
+type tempFileReader struct {
+	*os.File
+}
+
+func (tf *tempFileReader) Close() error {
+	err := tf.File.Close()
+	os.Remove(tf.File.Name())
+	return err
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Create an engine that synthesizes with backend (a FakeBackend when nil), with an
+// English and a Spanish model
+func newTestEngine(t *testing.T, backend Backend) *Engine {
+	t.Helper()
+	return newTestEngineWithConfig(t, Config{Backend: backend})
+}
+
+func newTestEngineWithConfig(t *testing.T, config Config) *Engine {
+	t.Helper()
+
+	if config.Backend == nil {
+		config.Backend = &FakeBackend{Frequency: 440, CharDuration: 10 * time.Millisecond}
+	}
+	if config.MaxConcurrent == 0 {
+		config.MaxConcurrent = 4
+	}
+
+	modelDir := t.TempDir()
+	models := map[string]string{
+		"en_US-test-medium": `{"modelcard": {"id": "en_US-test", "name": "Test English", "language": "en"}}`,
+		"es_MX-test-medium": `{"modelcard": {"id": "es_MX-test", "name": "Test Spanish", "language": "es"}}`,
+	}
+	for name, card := range models {
+		if err := os.WriteFile(filepath.Join(modelDir, name+".onnx"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modelDir, name+".onnx.json"), []byte(card), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.ModelPaths = []string{modelDir}
+
+	e, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	return e
+}
+
+// Path of a test model by ID
+func testModelPath(t *testing.T, e *Engine, id string) string {
+	t.Helper()
+
+	model, err := e.FindModelByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.OnnxPath
+}
@@ -0,0 +1,38 @@
+package engine
+
+import "errors"
+
+// Kinds of errors returned by Prepare and the lexicon store, to be checked with errors.Is.
+// The errors themselves carry a message meant for users.
+var (
+	// Something in the request is missing or out of range
+	ErrInvalidRequest = errors.New("invalid request")
+	// A model or lexicon entry doesn't exist
+	ErrNotFound = errors.New("not found")
+	// The entry belongs to a file that can't be edited
+	ErrReadOnly = errors.New("read-only")
+	// Nothing was left to read once the text was filtered
+	ErrNoSentences = errors.New("No valid sentences found in text")
+)
+
+// kindError is an error with a user-facing message that also matches one of the kinds above
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (ke *kindError) Error() string {
+	return ke.err.Error()
+}
+
+func (ke *kindError) Unwrap() []error {
+	return []error{ke.kind, ke.err}
+}
+
+func invalidRequest(err error) error {
+	return &kindError{kind: ErrInvalidRequest, err: err}
+}
+
+func notFound(err error) error {
+	return &kindError{kind: ErrNotFound, err: err}
+}
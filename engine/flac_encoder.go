@@ -0,0 +1,394 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+
+	"github.com/go-audio/audio"
+)
+
+// Samples per channel in each FLAC frame
+const flacBlockSize = 4096
+
+// Highest Rice partition order tried; 4096 >> 8 = 16 samples per partition
+const flacMaxPartitionOrder = 8
+
+// Encode a PCM buffer as a FLAC file using fixed linear predictors and Rice coding.
+// This is lossless and typically halves the size of speech WAVs.
+func writeFLACFile(filePath string, buffer *audio.IntBuffer, header *WAVHeader) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("error creating FLAC file: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := encodeFLAC(writer, buffer, header); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func encodeFLAC(w io.Writer, buffer *audio.IntBuffer, header *WAVHeader) error {
+	channels := int(header.NumChannels)
+	bps := int(header.BitsPerSample)
+	if channels < 1 || channels > 8 {
+		return fmt.Errorf("FLAC supports 1 to 8 channels, got %d", channels)
+	}
+	if bps < 4 || bps > 24 {
+		return fmt.Errorf("FLAC encoder supports 4 to 24 bits per sample, got %d", bps)
+	}
+
+	// FLAC samples are signed, while 8-bit WAV is unsigned
+	samples := buffer.Data
+	if bps == 8 {
+		samples = make([]int, len(buffer.Data))
+		for i, sample := range buffer.Data {
+			samples[i] = sample - 128
+		}
+	}
+
+	totalFrames := len(samples) / channels
+
+	if _, err := w.Write([]byte("fLaC")); err != nil {
+		return err
+	}
+	if err := writeFLACStreamInfo(w, samples, header, totalFrames); err != nil {
+		return err
+	}
+
+	channelData := make([][]int, channels)
+	for frameNumber, start := 0, 0; start < totalFrames; frameNumber, start = frameNumber+1, start+flacBlockSize {
+		end := start + flacBlockSize
+		if end > totalFrames {
+			end = totalFrames
+		}
+
+		// De-interleave this block
+		for ch := 0; ch < channels; ch++ {
+			channelData[ch] = channelData[ch][:0]
+			for i := start; i < end; i++ {
+				channelData[ch] = append(channelData[ch], samples[i*channels+ch])
+			}
+		}
+
+		frame := encodeFLACFrame(channelData, frameNumber, bps)
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the (last and only) metadata block
+func writeFLACStreamInfo(w io.Writer, samples []int, header *WAVHeader, totalFrames int) error {
+	bw := &flacBitWriter{}
+
+	// Block header: last-metadata-block flag, type 0 (STREAMINFO), length 34
+	bw.write(1, 1)
+	bw.write(0, 7)
+	bw.write(34, 24)
+
+	minBlock := flacBlockSize
+	if totalFrames < flacBlockSize {
+		minBlock = totalFrames
+	}
+	bw.write(uint64(minBlock), 16)
+	bw.write(flacBlockSize, 16)
+	bw.write(0, 24) // min frame size unknown
+	bw.write(0, 24) // max frame size unknown
+	bw.write(uint64(header.SampleRate), 20)
+	bw.write(uint64(header.NumChannels-1), 3)
+	bw.write(uint64(header.BitsPerSample-1), 5)
+	bw.write(uint64(totalFrames), 36)
+
+	// MD5 of the signed, little-endian, interleaved samples
+	hash := md5.New()
+	bytesPerSample := (int(header.BitsPerSample) + 7) / 8
+	sampleBytes := make([]byte, 4)
+	for _, sample := range samples {
+		binary.LittleEndian.PutUint32(sampleBytes, uint32(int32(sample)))
+		hash.Write(sampleBytes[:bytesPerSample])
+	}
+
+	out := append(bw.bytes(), hash.Sum(nil)...)
+	_, err := w.Write(out)
+	return err
+}
+
+func encodeFLACFrame(channelData [][]int, frameNumber, bps int) []byte {
+	blockSize := len(channelData[0])
+	bw := &flacBitWriter{}
+
+	// Frame header
+	bw.write(0x3FFE, 14) // sync code
+	bw.write(0, 1)       // reserved
+	bw.write(0, 1)       // fixed block size stream
+	bw.write(7, 4)       // block size stored as 16 bits at the end of the header
+	bw.write(0, 4)       // sample rate from STREAMINFO
+	bw.write(uint64(len(channelData)-1), 4)
+	bw.write(0, 3) // sample size from STREAMINFO
+	bw.write(0, 1) // reserved
+	bw.writeUTF8(uint64(frameNumber))
+	bw.write(uint64(blockSize-1), 16)
+	bw.write(uint64(flacCRC8(bw.bytes())), 8)
+
+	for _, data := range channelData {
+		encodeFLACSubframe(bw, data, bps)
+	}
+
+	bw.align()
+	frame := bw.bytes()
+
+	crc := flacCRC16(frame)
+	return append(frame, byte(crc>>8), byte(crc))
+}
+
+func encodeFLACSubframe(bw *flacBitWriter, data []int, bps int) {
+	// Silence and other constant runs take a single sample
+	constant := true
+	for _, sample := range data[1:] {
+		if sample != data[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		bw.write(0, 1)
+		bw.write(0, 6)
+		bw.write(0, 1)
+		bw.writeSigned(int64(data[0]), bps)
+		return
+	}
+
+	// Pick the fixed predictor with the smallest residual
+	bestOrder := 0
+	var bestResidual []int64
+	var bestSum uint64
+	for order := 0; order <= 4 && order < len(data); order++ {
+		residual, sum := flacFixedResidual(data, order)
+		if bestResidual == nil || sum < bestSum {
+			bestOrder, bestResidual, bestSum = order, residual, sum
+		}
+	}
+
+	bw.write(0, 1)
+	bw.write(uint64(8|bestOrder), 6) // 001xxx = FIXED with order xxx
+	bw.write(0, 1)
+
+	// Warm-up samples are stored verbatim
+	for i := 0; i < bestOrder; i++ {
+		bw.writeSigned(int64(data[i]), bps)
+	}
+
+	writeFLACResidual(bw, bestResidual, len(data), bestOrder)
+}
+
+// Residual of the fixed polynomial predictor of the given order, zigzag encoded
+func flacFixedResidual(data []int, order int) ([]int64, uint64) {
+	residual := make([]int64, 0, len(data)-order)
+	var sum uint64
+
+	for i := order; i < len(data); i++ {
+		var prediction int64
+		switch order {
+		case 1:
+			prediction = int64(data[i-1])
+		case 2:
+			prediction = 2*int64(data[i-1]) - int64(data[i-2])
+		case 3:
+			prediction = 3*int64(data[i-1]) - 3*int64(data[i-2]) + int64(data[i-3])
+		case 4:
+			prediction = 4*int64(data[i-1]) - 6*int64(data[i-2]) + 4*int64(data[i-3]) - int64(data[i-4])
+		}
+
+		r := int64(data[i]) - prediction
+		zigzag := (r << 1) ^ (r >> 63)
+		residual = append(residual, zigzag)
+		sum += uint64(zigzag)
+	}
+
+	return residual, sum
+}
+
+// Write a Rice-coded residual, choosing the partition order with the smallest estimated size
+func writeFLACResidual(bw *flacBitWriter, residual []int64, blockSize, predictorOrder int) {
+	bestOrder := 0
+	bestCost := uint64(1<<63 - 1)
+	var bestParams []uint
+
+	for partitionOrder := 0; partitionOrder <= flacMaxPartitionOrder; partitionOrder++ {
+		partitions := 1 << partitionOrder
+		if blockSize%partitions != 0 || blockSize/partitions <= predictorOrder {
+			break
+		}
+
+		params := make([]uint, partitions)
+		var cost uint64
+		offset := 0
+		for p := 0; p < partitions; p++ {
+			count := blockSize / partitions
+			if p == 0 {
+				count -= predictorOrder
+			}
+
+			var sum uint64
+			for _, value := range residual[offset : offset+count] {
+				sum += uint64(value)
+			}
+			offset += count
+
+			param, partitionCost := flacRiceParameter(sum, count)
+			params[p] = param
+			cost += partitionCost + 4
+		}
+
+		if cost < bestCost {
+			bestOrder, bestCost, bestParams = partitionOrder, cost, params
+		}
+	}
+
+	bw.write(0, 2) // Rice coding with 4-bit parameters
+	bw.write(uint64(bestOrder), 4)
+
+	partitions := 1 << bestOrder
+	offset := 0
+	for p := 0; p < partitions; p++ {
+		count := blockSize / partitions
+		if p == 0 {
+			count -= predictorOrder
+		}
+
+		param := bestParams[p]
+		bw.write(uint64(param), 4)
+		for _, value := range residual[offset : offset+count] {
+			bw.writeRice(uint64(value), param)
+		}
+		offset += count
+	}
+}
+
+// Estimate the best Rice parameter for a partition and its size in bits
+func flacRiceParameter(sum uint64, count int) (uint, uint64) {
+	if count == 0 {
+		return 0, 0
+	}
+
+	param := uint(0)
+	if mean := sum / uint64(count); mean > 0 {
+		param = uint(bits.Len64(mean)) - 1
+	}
+	// 15 is the escape code
+	if param > 14 {
+		param = 14
+	}
+
+	cost := uint64(count)*uint64(param+1) + (sum >> param)
+	return param, cost
+}
+
+// flacBitWriter packs values MSB first
+type flacBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *flacBitWriter) write(value uint64, n uint) {
+	for n > 0 {
+		take := n
+		if take > 32 {
+			take = 32
+		}
+		n -= take
+		chunk := (value >> n) & (1<<take - 1)
+
+		bw.acc = bw.acc<<take | chunk
+		bw.nbits += take
+		for bw.nbits >= 8 {
+			bw.nbits -= 8
+			bw.buf = append(bw.buf, byte(bw.acc>>bw.nbits))
+		}
+		bw.acc &= 1<<bw.nbits - 1
+	}
+}
+
+func (bw *flacBitWriter) writeSigned(value int64, n int) {
+	bw.write(uint64(value)&(1<<uint(n)-1), uint(n))
+}
+
+// Unary quotient followed by param low bits
+func (bw *flacBitWriter) writeRice(value uint64, param uint) {
+	quotient := value >> param
+	for quotient >= 32 {
+		bw.write(0, 32)
+		quotient -= 32
+	}
+	bw.write(1, uint(quotient)+1)
+	bw.write(value&(1<<param-1), param)
+}
+
+// Frame numbers use the same variable-length coding as UTF-8
+func (bw *flacBitWriter) writeUTF8(value uint64) {
+	if value < 0x80 {
+		bw.write(value, 8)
+		return
+	}
+
+	extra := 1
+	for value >= 1<<(uint(extra)*5+6) {
+		extra++
+	}
+
+	lead := uint64(0xFF<<(7-extra)) & 0xFF
+	bw.write(lead|(value>>(uint(extra)*6)), 8)
+	for i := extra - 1; i >= 0; i-- {
+		bw.write(0x80|((value>>(uint(i)*6))&0x3F), 8)
+	}
+}
+
+func (bw *flacBitWriter) align() {
+	if bw.nbits > 0 {
+		bw.write(0, 8-bw.nbits)
+	}
+}
+
+func (bw *flacBitWriter) bytes() []byte {
+	return bw.buf
+}
+
+func flacCRC8(data []byte) uint8 {
+	var crc uint8
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func flacCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,303 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+func TestFLACChecksums(t *testing.T) {
+	// Check values of CRC-8/SMBUS and CRC-16/UMTS, the ones FLAC uses
+	if crc := flacCRC8([]byte("123456789")); crc != 0xF4 {
+		t.Errorf("CRC-8 = %#x, want 0xf4", crc)
+	}
+	if crc := flacCRC16([]byte("123456789")); crc != 0xFEE8 {
+		t.Errorf("CRC-16 = %#x, want 0xfee8", crc)
+	}
+}
+
+func TestFLACRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sine := func(frames, channels, amplitude int) []int {
+		data := make([]int, frames*channels)
+		for i := range data {
+			data[i] = int(float64(amplitude)*math.Sin(2*math.Pi*440*float64(i/channels)/22050)) + random.Intn(64) - 32
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		channels int
+		bps      int
+		data     []int
+	}{
+		{"16-bit mono", 1, 16, sine(10000, 1, 10000)},
+		// Over 128 frames, so frame numbers take two bytes
+		{"16-bit mono, long", 1, 16, sine(130*flacBlockSize+7, 1, 20000)},
+		{"16-bit stereo", 2, 16, sine(5000, 2, 30000)},
+		{"silence", 1, 16, make([]int, 5000)},
+		{"single sample", 1, 16, []int{1234}},
+		{"8-bit", 1, 8, func() []int {
+			data := sine(3000, 1, 100)
+			for i := range data {
+				data[i] += 128
+			}
+			return data
+		}()},
+		{"24-bit noise", 1, 24, func() []int {
+			data := make([]int, 5000)
+			for i := range data {
+				data[i] = random.Intn(1<<24) - 1<<23
+			}
+			return data
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := &WAVHeader{SampleRate: 22050, NumChannels: uint16(tt.channels), BitsPerSample: uint16(tt.bps)}
+			buffer := &audio.IntBuffer{Data: tt.data, Format: &audio.Format{NumChannels: tt.channels, SampleRate: 22050}}
+
+			var encoded bytes.Buffer
+			if err := encodeFLAC(&encoded, buffer, header); err != nil {
+				t.Fatal(err)
+			}
+
+			info, decoded, err := decodeTestFLAC(encoded.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.sampleRate != 22050 || info.channels != tt.channels || info.bps != tt.bps {
+				t.Errorf("STREAMINFO says %d Hz, %d channels, %d bits", info.sampleRate, info.channels, info.bps)
+			}
+			if info.totalFrames != len(tt.data)/tt.channels {
+				t.Errorf("STREAMINFO says %d frames, want %d", info.totalFrames, len(tt.data)/tt.channels)
+			}
+
+			// FLAC holds signed samples; 8-bit WAV is unsigned
+			want := tt.data
+			if tt.bps == 8 {
+				want = make([]int, len(tt.data))
+				for i, sample := range tt.data {
+					want[i] = sample - 128
+				}
+			}
+			if len(decoded) != len(want) {
+				t.Fatalf("decoded %d samples, want %d", len(decoded), len(want))
+			}
+			for i := range want {
+				if decoded[i] != want[i] {
+					t.Fatalf("sample %d decoded as %d, want %d", i, decoded[i], want[i])
+				}
+			}
+
+			hash := md5.New()
+			sampleBytes := make([]byte, 4)
+			for _, sample := range decoded {
+				binary.LittleEndian.PutUint32(sampleBytes, uint32(int32(sample)))
+				hash.Write(sampleBytes[:(tt.bps+7)/8])
+			}
+			if !bytes.Equal(hash.Sum(nil), info.md5) {
+				t.Error("STREAMINFO MD5 doesn't match the decoded audio")
+			}
+
+			if tt.name == "16-bit mono" && encoded.Len() >= len(tt.data)*2 {
+				t.Errorf("%d bytes of FLAC for %d bytes of PCM", encoded.Len(), len(tt.data)*2)
+			}
+		})
+	}
+}
+
+type testFLACInfo struct {
+	sampleRate, channels, bps, totalFrames int
+	md5                                    []byte
+}
+
+// Decode the subset of FLAC encodeFLAC writes (one STREAMINFO block; constant and fixed
+// subframes with Rice coding), checking every frame's sync code and CRCs
+func decodeTestFLAC(data []byte) (testFLACInfo, []int, error) {
+	var info testFLACInfo
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return info, nil, fmt.Errorf("missing fLaC marker")
+	}
+
+	br := &testBitReader{data: data, pos: 32}
+	if last, blockType, length := br.read(1), br.read(7), br.read(24); last != 1 || blockType != 0 || length != 34 {
+		return info, nil, fmt.Errorf("unexpected metadata block: last %d, type %d, length %d", last, blockType, length)
+	}
+	br.read(16 + 16 + 24 + 24)
+	info.sampleRate = int(br.read(20))
+	info.channels = int(br.read(3)) + 1
+	info.bps = int(br.read(5)) + 1
+	info.totalFrames = int(br.read(36))
+	for i := 0; i < 16; i++ {
+		info.md5 = append(info.md5, byte(br.read(8)))
+	}
+
+	samples := []int{}
+	for frameNumber := 0; br.pos/8 < len(data); frameNumber++ {
+		frameStart := br.pos / 8
+		if sync := br.read(14); sync != 0x3FFE {
+			return info, nil, fmt.Errorf("frame %d: bad sync code %#x", frameNumber, sync)
+		}
+		br.read(1 + 1)
+		if code := br.read(4); code != 7 {
+			return info, nil, fmt.Errorf("frame %d: block size code %d", frameNumber, code)
+		}
+		br.read(4)
+		if channels := int(br.read(4)) + 1; channels != info.channels {
+			return info, nil, fmt.Errorf("frame %d: %d channels", frameNumber, channels)
+		}
+		br.read(3 + 1)
+
+		if number := br.readUTF8(); number != frameNumber {
+			return info, nil, fmt.Errorf("frame %d numbered %d", frameNumber, number)
+		}
+		blockSize := int(br.read(16)) + 1
+		if crc := uint8(br.read(8)); crc != flacCRC8(data[frameStart:br.pos/8-1]) {
+			return info, nil, fmt.Errorf("frame %d: bad header CRC", frameNumber)
+		}
+
+		channelData := make([][]int, info.channels)
+		for ch := range channelData {
+			decoded, err := br.readSubframe(blockSize, info.bps)
+			if err != nil {
+				return info, nil, fmt.Errorf("frame %d, channel %d: %v", frameNumber, ch, err)
+			}
+			channelData[ch] = decoded
+		}
+
+		br.align()
+		frameEnd := br.pos / 8
+		if crc := uint16(br.read(16)); crc != flacCRC16(data[frameStart:frameEnd]) {
+			return info, nil, fmt.Errorf("frame %d: bad frame CRC", frameNumber)
+		}
+
+		for i := 0; i < blockSize; i++ {
+			for ch := range channelData {
+				samples = append(samples, channelData[ch][i])
+			}
+		}
+	}
+
+	return info, samples, nil
+}
+
+type testBitReader struct {
+	data []byte
+	pos  int
+}
+
+func (br *testBitReader) read(n int) uint64 {
+	var value uint64
+	for i := 0; i < n; i++ {
+		bit := br.data[br.pos/8] >> (7 - uint(br.pos%8)) & 1
+		value = value<<1 | uint64(bit)
+		br.pos++
+	}
+	return value
+}
+
+func (br *testBitReader) readSigned(n int) int {
+	value := br.read(n)
+	if value&(1<<uint(n-1)) != 0 {
+		return int(int64(value) - 1<<uint(n))
+	}
+	return int(value)
+}
+
+func (br *testBitReader) readUTF8() int {
+	first := br.read(8)
+	extra := 0
+	for mask := uint64(0x80); first&mask != 0; mask >>= 1 {
+		extra++
+	}
+	if extra == 0 {
+		return int(first)
+	}
+
+	value := first & (0xFF >> uint(extra+1))
+	for i := 1; i < extra; i++ {
+		value = value<<6 | br.read(8)&0x3F
+	}
+	return int(value)
+}
+
+func (br *testBitReader) align() {
+	br.pos = (br.pos + 7) / 8 * 8
+}
+
+func (br *testBitReader) readSubframe(blockSize, bps int) ([]int, error) {
+	br.read(1)
+	subframeType := int(br.read(6))
+	if wasted := br.read(1); wasted != 0 {
+		return nil, fmt.Errorf("unexpected wasted bits")
+	}
+
+	if subframeType == 0 {
+		value := br.readSigned(bps)
+		data := make([]int, blockSize)
+		for i := range data {
+			data[i] = value
+		}
+		return data, nil
+	}
+	if subframeType < 8 || subframeType > 12 {
+		return nil, fmt.Errorf("unexpected subframe type %d", subframeType)
+	}
+
+	order := subframeType & 7
+	data := make([]int, 0, blockSize)
+	for i := 0; i < order; i++ {
+		data = append(data, br.readSigned(bps))
+	}
+
+	if method := br.read(2); method != 0 {
+		return nil, fmt.Errorf("unexpected residual coding %d", method)
+	}
+	partitionOrder := int(br.read(4))
+	partitions := 1 << uint(partitionOrder)
+	for p := 0; p < partitions; p++ {
+		param := uint(br.read(4))
+		if param == 15 {
+			return nil, fmt.Errorf("unexpected escaped partition")
+		}
+		count := blockSize / partitions
+		if p == 0 {
+			count -= order
+		}
+
+		for i := 0; i < count; i++ {
+			quotient := uint64(0)
+			for br.read(1) == 0 {
+				quotient++
+			}
+			zigzag := quotient<<param | br.read(int(param))
+			residual := int64(zigzag>>1) ^ -int64(zigzag&1)
+
+			n := len(data)
+			var prediction int64
+			switch order {
+			case 1:
+				prediction = int64(data[n-1])
+			case 2:
+				prediction = 2*int64(data[n-1]) - int64(data[n-2])
+			case 3:
+				prediction = 3*int64(data[n-1]) - 3*int64(data[n-2]) + int64(data[n-3])
+			case 4:
+				prediction = 4*int64(data[n-1]) - 6*int64(data[n-2]) + 4*int64(data[n-3]) - int64(data[n-4])
+			}
+			data = append(data, int(prediction+residual))
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Character n-grams from 1 to languageNgramOrder letters are used as features
+const languageNgramOrder = 3
+
+// Below this many letters a detection is too unreliable to switch voices on
+const minDetectionLetters = 12
+
+// Log-likelihood difference between the two best languages needed to trust a detection
+const minDetectionMargin = 3.0
+
+// N-gram frequencies of one language, as smoothed log probabilities
+type languageProfile struct {
+	logProbs map[string]float64
+	// Log probability of an n-gram never seen in the samples
+	unseen float64
+}
+
+var languageProfiles = buildLanguageProfiles(languageSamples)
+
+// Language codes identifyLanguage can tell apart, sorted
+func detectableLanguages() []string {
+	languages := make([]string, 0, len(languageProfiles))
+	for lang := range languageProfiles {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// LanguageGuess is the result of identifying the language of a text
+type LanguageGuess struct {
+	Language string
+	// Whether the text was long and distinct enough to trust the guess
+	Confident bool
+}
+
+// Naive Bayes over character n-grams, trained on the built-in samples
+func buildLanguageProfiles(samples map[string]string) map[string]*languageProfile {
+	counts := make(map[string]map[string]int)
+	vocabulary := make(map[string]bool)
+
+	for lang, sample := range samples {
+		counts[lang] = make(map[string]int)
+		for _, gram := range textNgrams(sample) {
+			counts[lang][gram]++
+			vocabulary[gram] = true
+		}
+	}
+
+	profiles := make(map[string]*languageProfile)
+	for lang, langCounts := range counts {
+		total := 0
+		for _, count := range langCounts {
+			total += count
+		}
+
+		// Add-half smoothing keeps unseen n-grams possible without drowning rare ones
+		denominator := float64(total) + 0.5*float64(len(vocabulary))
+		profile := &languageProfile{
+			logProbs: make(map[string]float64, len(langCounts)),
+			unseen:   math.Log(0.5 / denominator),
+		}
+		for gram, count := range langCounts {
+			profile.logProbs[gram] = math.Log((float64(count) + 0.5) / denominator)
+		}
+		profiles[lang] = profile
+	}
+
+	return profiles
+}
+
+// Lower-cased n-grams of every word, padded with spaces so word starts and ends count.
+// Inverted Spanish marks are kept since they give the language away.
+func textNgrams(text string) []string {
+	grams := []string{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '¿' && r != '¡' && r != '\''
+	})
+
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= languageNgramOrder; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram != " " {
+					grams = append(grams, gram)
+				}
+			}
+		}
+	}
+
+	return grams
+}
+
+// Identify the language of text among candidates (language codes). Candidates
+// without a built-in profile can't be detected; with a single one it always wins.
+func identifyLanguage(text string, candidates []string) LanguageGuess {
+	known := []string{}
+	for _, lang := range candidates {
+		if languageProfiles[lang] != nil {
+			known = append(known, lang)
+		}
+	}
+	sort.Strings(known)
+
+	if len(known) == 0 {
+		return LanguageGuess{}
+	}
+	if len(known) == 1 {
+		return LanguageGuess{Language: known[0], Confident: true}
+	}
+
+	grams := textNgrams(text)
+	scores := make(map[string]float64, len(known))
+	for _, lang := range known {
+		profile := languageProfiles[lang]
+		score := 0.0
+		for _, gram := range grams {
+			if logProb, ok := profile.logProbs[gram]; ok {
+				score += logProb
+			} else {
+				score += profile.unseen
+			}
+		}
+		scores[lang] = score
+	}
+
+	sort.SliceStable(known, func(i, j int) bool {
+		return scores[known[i]] > scores[known[j]]
+	})
+
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+
+	return LanguageGuess{
+		Language:  known[0],
+		Confident: letters >= minDetectionLetters && scores[known[0]]-scores[known[1]] >= minDetectionMargin,
+	}
+}
+
+// Value of modelPath that picks a voice for every sentence by its language
+const AutoModelPath = "auto"
+
+// Where a run of text in one language ends: after a sentence, or at a line break
+var languageBoundaryPattern = regexp.MustCompile(`[.!?…]+["'”»)\]]*\s+|\n\s*`)
+
+// VoiceSelector picks a model for text by its detected language
+type VoiceSelector struct {
+	// Models by language code
+	voices map[string]*Model
+	// The request's modelPath, used when the language can't be told; may be nil
+	fallback *Model
+}
+
+// LanguageRun is a stretch of text in one language and the voice chosen for it
+type LanguageRun struct {
+	Text     string
+	Language string
+	Model    *Model
+}
+
+// Build a selector from "auto" (one model per language among the scanned ones) and/or
+// a voiceByLanguage map of language codes to model IDs or paths, which takes precedence.
+// Any other modelPath is used for languages without a voice.
+func (e *Engine) newVoiceSelector(modelPath string, voiceByLanguage map[string]string) (*VoiceSelector, error) {
+	selector := &VoiceSelector{voices: make(map[string]*Model)}
+
+	if modelPath == AutoModelPath {
+		models := e.modelList()
+		for i := range models {
+			lang := languageCode(models[i].Language)
+			if _, ok := selector.voices[lang]; !ok && languageProfiles[lang] != nil {
+				selector.voices[lang] = &models[i]
+			}
+		}
+	} else if modelPath != "" {
+		model, err := e.FindModelByPath(modelPath)
+		if err != nil {
+			return nil, fmt.Errorf("Model not found")
+		}
+		selector.fallback = model
+	}
+
+	for lang, voice := range voiceByLanguage {
+		if languageProfiles[languageCode(lang)] == nil {
+			return nil, fmt.Errorf("Language %s can't be detected; voiceByLanguage supports %s", lang, strings.Join(detectableLanguages(), ", "))
+		}
+
+		model, err := e.FindModelByPath(voice)
+		if err != nil {
+			model, err = e.FindModelByID(voice)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Voice for %s not found: %s", lang, voice)
+		}
+		selector.voices[languageCode(lang)] = model
+	}
+
+	if selector.fallback != nil {
+		lang := languageCode(selector.fallback.Language)
+		if _, ok := selector.voices[lang]; !ok {
+			selector.voices[lang] = selector.fallback
+		}
+	}
+
+	if len(selector.voices) == 0 {
+		return nil, fmt.Errorf("No models with a detectable language to choose from")
+	}
+	return selector, nil
+}
+
+// Languages the selector has voices for, sorted
+func (vs *VoiceSelector) languages() []string {
+	languages := make([]string, 0, len(vs.voices))
+	for lang := range vs.voices {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Model for a detected language, falling back to modelPath and then to any voice
+func (vs *VoiceSelector) modelFor(lang string) *Model {
+	if model, ok := vs.voices[lang]; ok {
+		return model
+	}
+	if vs.fallback != nil {
+		return vs.fallback
+	}
+	return vs.voices[vs.languages()[0]]
+}
+
+// Split text at sentence ends and line breaks, detect the language of every piece and
+// join neighbouring pieces of the same language. Pieces too short or unclear to tell
+// keep the language before them (or of the whole text, at the start).
+func (vs *VoiceSelector) Split(text string) []LanguageRun {
+	candidates := vs.languages()
+	language := identifyLanguage(text, candidates).Language
+
+	runs := []LanguageRun{}
+	start := 0
+	ends := languageBoundaryPattern.FindAllStringIndex(text, -1)
+	ends = append(ends, []int{len(text), len(text)})
+
+	for _, end := range ends {
+		piece := text[start:end[1]]
+		start = end[1]
+		if strings.TrimSpace(piece) == "" {
+			if len(runs) > 0 {
+				runs[len(runs)-1].Text += piece
+			}
+			continue
+		}
+
+		if guess := identifyLanguage(piece, candidates); guess.Confident {
+			language = guess.Language
+		}
+
+		if len(runs) > 0 && runs[len(runs)-1].Language == language {
+			runs[len(runs)-1].Text += piece
+			continue
+		}
+		runs = append(runs, LanguageRun{Text: piece, Language: language, Model: vs.modelFor(language)})
+	}
+
+	return runs
+}
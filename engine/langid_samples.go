@@ -0,0 +1,95 @@
+package engine
+
+// Sample text the language profiles are trained on. Everyday prose with the
+// common function words of each language matters more than its length.
+var languageSamples = map[string]string{
+	"es": `El día empezó con lluvia, pero por la tarde salió el sol y la gente volvió a llenar las calles del centro.
+Mi hermana trabaja en un hospital desde hace diez años y todavía dice que cada guardia es diferente.
+¿Has visto las noticias de hoy? Dicen que el gobierno va a cambiar la ley de vivienda antes del verano.
+Queremos que el proyecto esté terminado cuando lleguen los nuevos compañeros, así que tenemos que darnos prisa.
+La biblioteca del barrio abre todos los días, incluso los domingos, y tiene una sección muy buena de libros para niños.
+No sé si podré ir a la reunión, porque mañana tengo que llevar el coche al taller y no sé cuánto tardarán.
+¡Qué alegría verte otra vez! Hacía muchísimo tiempo que no hablábamos con tranquilidad.
+Los resultados de la encuesta muestran que la mayoría de los usuarios prefiere una aplicación más sencilla.
+Para preparar la salsa, corta la cebolla en trozos pequeños y cocínala a fuego lento hasta que esté dorada.
+Aunque el tren salió con retraso, llegamos a tiempo para la cena y nos quedamos charlando hasta medianoche.
+También es importante que cada persona pueda elegir cómo quiere recibir la información y en qué idioma.
+Ella siempre dice que la música le ayuda a concentrarse, sobre todo cuando estudia por la noche.
+Este año la empresa ha contratado a más de cien personas y piensa abrir una oficina nueva en el norte.
+Nosotros vivimos cerca del mar, así que en invierno hace viento pero en verano se está muy bien.`,
+
+	"en": `The day started with rain, but in the afternoon the sun came out and people filled the streets again.
+My sister has worked at the hospital for ten years and she still says that every shift is different.
+Have you seen the news today? They say the government is going to change the housing law before the summer.
+We want the project to be finished when the new team members arrive, so we need to hurry up.
+The local library is open every day, even on Sundays, and it has a very good section of books for children.
+I don't know if I can make it to the meeting, because tomorrow I have to take the car to the garage.
+What a joy to see you again! It has been such a long time since we had a quiet conversation.
+The results of the survey show that most of the users would rather have a simpler application.
+To make the sauce, cut the onion into small pieces and cook it slowly until it turns golden.
+Although the train left late, we arrived in time for dinner and stayed talking until midnight.
+It is also important that everyone can choose how they want to receive the information and in which language.
+She always says that music helps her focus, especially when she is studying at night.
+This year the company has hired more than a hundred people and it is planning to open a new office in the north.
+We live near the sea, so in winter it gets windy, but in summer the weather is really nice.`,
+
+	"pt": `O dia começou com chuva, mas à tarde o sol apareceu e as pessoas voltaram a encher as ruas do centro.
+A minha irmã trabalha no hospital há dez anos e ainda diz que cada plantão é diferente.
+Você viu as notícias de hoje? Dizem que o governo vai mudar a lei da habitação antes do verão.
+Queremos que o projeto esteja pronto quando chegarem os novos colegas, então precisamos de nos apressar.
+A biblioteca do bairro abre todos os dias, até aos domingos, e tem uma secção muito boa de livros para crianças.
+Não sei se vou conseguir ir à reunião, porque amanhã tenho de levar o carro à oficina.
+Que alegria ver você de novo! Fazia muito tempo que não conversávamos com calma.
+Os resultados do inquérito mostram que a maioria dos utilizadores prefere uma aplicação mais simples.
+Para preparar o molho, corte a cebola em pedaços pequenos e cozinhe em lume brando até ficar dourada.
+Embora o comboio tenha saído atrasado, chegámos a tempo do jantar e ficámos a conversar até à meia-noite.
+Também é importante que cada pessoa possa escolher como quer receber a informação e em que língua.
+Ela diz sempre que a música a ajuda a concentrar-se, sobretudo quando estuda à noite.
+Este ano a empresa contratou mais de cem pessoas e pensa abrir um novo escritório no norte.
+Nós moramos perto do mar, por isso no inverno há vento, mas no verão está muito bom.`,
+
+	"fr": `La journée a commencé sous la pluie, mais l'après-midi le soleil est sorti et les gens ont de nouveau rempli les rues.
+Ma sœur travaille à l'hôpital depuis dix ans et elle dit toujours que chaque garde est différente.
+As-tu vu les informations aujourd'hui ? On dit que le gouvernement va changer la loi sur le logement avant l'été.
+Nous voulons que le projet soit terminé quand les nouveaux collègues arriveront, alors il faut se dépêcher.
+La bibliothèque du quartier est ouverte tous les jours, même le dimanche, et elle a un très bon rayon de livres pour enfants.
+Je ne sais pas si je pourrai venir à la réunion, parce que demain je dois emmener la voiture au garage.
+Quelle joie de te revoir ! Cela faisait très longtemps que nous n'avions pas parlé tranquillement.
+Les résultats de l'enquête montrent que la plupart des utilisateurs préfèrent une application plus simple.
+Pour préparer la sauce, coupez l'oignon en petits morceaux et faites-le cuire à feu doux jusqu'à ce qu'il soit doré.
+Bien que le train soit parti en retard, nous sommes arrivés à temps pour le dîner et nous avons discuté jusqu'à minuit.
+Il est aussi important que chacun puisse choisir comment il veut recevoir l'information et dans quelle langue.
+Elle dit toujours que la musique l'aide à se concentrer, surtout quand elle étudie le soir.
+Cette année l'entreprise a embauché plus de cent personnes et elle pense ouvrir un nouveau bureau dans le nord.
+Nous habitons près de la mer, donc en hiver il y a du vent, mais en été il fait vraiment bon.`,
+
+	"de": `Der Tag begann mit Regen, aber am Nachmittag kam die Sonne heraus und die Leute füllten wieder die Straßen.
+Meine Schwester arbeitet seit zehn Jahren im Krankenhaus und sagt immer noch, dass jede Schicht anders ist.
+Hast du heute die Nachrichten gesehen? Es heißt, die Regierung will das Wohnungsgesetz vor dem Sommer ändern.
+Wir wollen, dass das Projekt fertig ist, wenn die neuen Kollegen kommen, deshalb müssen wir uns beeilen.
+Die Bücherei im Viertel ist jeden Tag geöffnet, sogar sonntags, und sie hat eine sehr gute Abteilung mit Kinderbüchern.
+Ich weiß nicht, ob ich zum Treffen kommen kann, weil ich morgen das Auto in die Werkstatt bringen muss.
+Was für eine Freude, dich wiederzusehen! Wir haben schon so lange nicht mehr in Ruhe miteinander gesprochen.
+Die Ergebnisse der Umfrage zeigen, dass die meisten Nutzer eine einfachere Anwendung bevorzugen.
+Für die Soße schneidest du die Zwiebel in kleine Stücke und lässt sie langsam goldbraun werden.
+Obwohl der Zug verspätet abgefahren ist, waren wir rechtzeitig zum Abendessen da und haben bis Mitternacht geredet.
+Es ist auch wichtig, dass jeder selbst wählen kann, wie er die Informationen bekommen möchte und in welcher Sprache.
+Sie sagt immer, dass ihr die Musik hilft, sich zu konzentrieren, vor allem wenn sie abends lernt.
+Dieses Jahr hat die Firma mehr als hundert Menschen eingestellt und plant ein neues Büro im Norden.
+Wir wohnen in der Nähe vom Meer, im Winter ist es windig, aber im Sommer ist das Wetter wirklich schön.`,
+
+	"it": `La giornata è cominciata con la pioggia, ma nel pomeriggio è uscito il sole e la gente ha riempito di nuovo le strade.
+Mia sorella lavora in ospedale da dieci anni e dice ancora che ogni turno è diverso.
+Hai visto il telegiornale oggi? Dicono che il governo cambierà la legge sulla casa prima dell'estate.
+Vogliamo che il progetto sia finito quando arriveranno i nuovi colleghi, quindi dobbiamo sbrigarci.
+La biblioteca del quartiere è aperta tutti i giorni, anche la domenica, e ha una sezione molto bella di libri per bambini.
+Non so se riuscirò a venire alla riunione, perché domani devo portare la macchina dal meccanico.
+Che gioia rivederti! Era da tantissimo tempo che non parlavamo con calma.
+I risultati del sondaggio mostrano che la maggior parte degli utenti preferisce un'applicazione più semplice.
+Per preparare il sugo, taglia la cipolla a pezzetti e cuocila a fuoco lento finché non diventa dorata.
+Anche se il treno è partito in ritardo, siamo arrivati in tempo per la cena e abbiamo chiacchierato fino a mezzanotte.
+È anche importante che ognuno possa scegliere come ricevere le informazioni e in quale lingua.
+Lei dice sempre che la musica la aiuta a concentrarsi, soprattutto quando studia la sera.
+Quest'anno l'azienda ha assunto più di cento persone e pensa di aprire un nuovo ufficio al nord.
+Noi abitiamo vicino al mare, quindi d'inverno c'è vento, ma d'estate si sta proprio bene.`,
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVoiceByLanguageRejectsUndetectableLanguages(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	_, err := e.Prepare(ConvertRequest{
+		Text:    "こんにちは。",
+		Options: Options{VoiceByLanguage: map[string]string{"ja": "en_US-test"}},
+	})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("got %v, want ErrInvalidRequest", err)
+	}
+	if !strings.Contains(err.Error(), "de, en, es, fr, it, pt") {
+		t.Errorf("error %q doesn't list the detectable languages", err)
+	}
+}
+
+func TestVoiceSelectorSplitsByLanguage(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	conv, err := e.Prepare(ConvertRequest{
+		Text:      "Bienvenidos al programa de esta semana. Today we talk about the new release of the project.",
+		ModelPath: AutoModelPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"es_MX-test", "en_US-test"}
+	if len(conv.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(conv.Items), len(want))
+	}
+	for i, item := range conv.Items {
+		if item.Model.ID != want[i] {
+			t.Errorf("item %d (%q) read by %s, want %s", i, item.SourceText, item.Model.ID, want[i])
+		}
+	}
+}
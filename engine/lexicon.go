@@ -0,0 +1,335 @@
+package engine
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LexiconEntry is one pronunciation fix of a shared lexicon
+type LexiconEntry struct {
+	ID string `json:"id"`
+	ReplacementRule
+	// File the entry was loaded from. Entries of PLS files can't be edited through the API.
+	Source   string `json:"source"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// Lexicons loaded from lexicon.<lang>.json and lexicon.<lang>.pls files in the model paths,
+// applied to every model of the language
+type LexiconStore struct {
+	mu sync.RWMutex
+	// Entries by lexicon key ("es", "es_mx"), in file order
+	entries map[string][]LexiconEntry
+	// JSON file that new entries of a key are saved to
+	files map[string]string
+	// Model paths the lexicons were loaded from; new files go in the first one
+	paths []string
+}
+
+// Layout of lexicon.<lang>.json
+type lexiconFile struct {
+	Entries []lexiconFileEntry `json:"entries"`
+}
+
+type lexiconFileEntry struct {
+	ID string `json:"id,omitempty"`
+	ReplacementRule
+}
+
+// The subset of W3C PLS that maps to text: graphemes read as an alias.
+// Phoneme-only lexemes can't be passed to piper and are skipped.
+type plsDocument struct {
+	Lexemes []struct {
+		Graphemes []string `xml:"grapheme"`
+		Alias     string   `xml:"alias"`
+	} `xml:"lexeme"`
+}
+
+func NewLexiconStore() *LexiconStore {
+	return &LexiconStore{
+		entries: make(map[string][]LexiconEntry),
+		files:   make(map[string]string),
+	}
+}
+
+// Key of a language in the store: its code, plus the region when there is one
+// ("Spanish" -> "es", "es_MX" and "es-mx" -> "es_mx")
+func LexiconKey(language string) string {
+	code := languageCode(language)
+	if region := languageRegion(language); region != "" && code != "" {
+		return code + "_" + strings.ToLower(region)
+	}
+	return code
+}
+
+// Reload every lexicon file found in the model paths
+func (ls *LexiconStore) Load(paths []string) {
+	entries := make(map[string][]LexiconEntry)
+	files := make(map[string]string)
+
+	for _, path := range paths {
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+
+		for _, dirEntry := range dirEntries {
+			name := dirEntry.Name()
+			ext := filepath.Ext(name)
+			if dirEntry.IsDir() || !strings.HasPrefix(name, "lexicon.") || (ext != ".json" && ext != ".pls") {
+				continue
+			}
+
+			key := LexiconKey(strings.TrimSuffix(strings.TrimPrefix(name, "lexicon."), ext))
+			if key == "" {
+				continue
+			}
+
+			file := filepath.Join(path, name)
+			var loaded []LexiconEntry
+			if ext == ".json" {
+				loaded, err = readLexiconJSON(file)
+				if _, ok := files[key]; !ok && err == nil {
+					files[key] = file
+				}
+			} else {
+				loaded, err = readLexiconPLS(file)
+			}
+			if err != nil {
+				log.Printf("[LEXICON] ❌ Error reading %s: %v", file, err)
+				continue
+			}
+
+			entries[key] = append(entries[key], loaded...)
+			log.Printf("[LEXICON] ✅ Loaded %d entries for %s from %s", len(loaded), key, name)
+		}
+	}
+
+	ls.mu.Lock()
+	ls.entries = entries
+	ls.files = files
+	ls.paths = append([]string{}, paths...)
+	ls.mu.Unlock()
+}
+
+func readLexiconJSON(file string) ([]LexiconEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lexicon lexiconFile
+	if err := json.Unmarshal(data, &lexicon); err != nil {
+		return nil, err
+	}
+
+	entries := []LexiconEntry{}
+	for i, fileEntry := range lexicon.Entries {
+		if err := fileEntry.Compile(); err != nil {
+			log.Printf("[LEXICON] ⚠️  Skipping entry %d of %s: %v", i+1, filepath.Base(file), err)
+			continue
+		}
+		if fileEntry.ID == "" {
+			fileEntry.ID = generateRandomString(4)
+		}
+		entries = append(entries, LexiconEntry{ID: fileEntry.ID, ReplacementRule: fileEntry.ReplacementRule, Source: file})
+	}
+	return entries, nil
+}
+
+func readLexiconPLS(file string) ([]LexiconEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var document plsDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	entries := []LexiconEntry{}
+	skipped := 0
+	for _, lexeme := range document.Lexemes {
+		alias := strings.TrimSpace(lexeme.Alias)
+		if alias == "" {
+			skipped++
+			continue
+		}
+
+		for _, grapheme := range lexeme.Graphemes {
+			rule := ReplacementRule{Find: strings.TrimSpace(grapheme), Replace: alias, CaseSensitive: true, WholeWord: true}
+			if err := rule.Compile(); err != nil {
+				continue
+			}
+			entries = append(entries, LexiconEntry{ID: generateRandomString(4), ReplacementRule: rule, Source: file, ReadOnly: true})
+		}
+	}
+
+	if skipped > 0 {
+		log.Printf("[LEXICON] ⚠️  Skipped %d lexemes without <alias> in %s (phonemes are not supported)", skipped, filepath.Base(file))
+	}
+	return entries, nil
+}
+
+// Rules for a model language: the entries of the language, then those of its region
+func (ls *LexiconStore) Rules(language string) []ReplacementRule {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	keys := []string{languageCode(language)}
+	if key := LexiconKey(language); key != keys[0] {
+		keys = append(keys, key)
+	}
+
+	rules := []ReplacementRule{}
+	for _, key := range keys {
+		for _, entry := range ls.entries[key] {
+			rules = append(rules, entry.ReplacementRule)
+		}
+	}
+	return rules
+}
+
+// Number of entries of every lexicon
+func (ls *LexiconStore) Languages() map[string]int {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	languages := make(map[string]int)
+	for key, entries := range ls.entries {
+		languages[key] = len(entries)
+	}
+	return languages
+}
+
+// Entries of one lexicon
+func (ls *LexiconStore) Entries(language string) []LexiconEntry {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	return append([]LexiconEntry{}, ls.entries[LexiconKey(language)]...)
+}
+
+// Add an entry and save it to the JSON lexicon of the language, which is created
+// in the first model path if needed. Invalid rules give an error matching ErrInvalidRequest.
+func (ls *LexiconStore) Add(language string, rule ReplacementRule) (LexiconEntry, error) {
+	key := LexiconKey(language)
+	if key == "" {
+		return LexiconEntry{}, invalidRequest(fmt.Errorf("Language is required"))
+	}
+	if err := rule.Compile(); err != nil {
+		return LexiconEntry{}, invalidRequest(err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	file, ok := ls.files[key]
+	if !ok {
+		if len(ls.paths) == 0 {
+			return LexiconEntry{}, fmt.Errorf("No model path to save the lexicon in")
+		}
+		file = filepath.Join(ls.paths[0], "lexicon."+key+".json")
+	}
+
+	entry := LexiconEntry{ID: generateRandomString(4), ReplacementRule: rule, Source: file}
+	previous := ls.entries[key]
+	ls.entries[key] = append(append([]LexiconEntry{}, previous...), entry)
+
+	if err := ls.save(file); err != nil {
+		ls.entries[key] = previous
+		return LexiconEntry{}, err
+	}
+	ls.files[key] = file
+
+	log.Printf("[LEXICON] ➕ Added '%s' → '%s' to %s", rule.Find, rule.Replace, key)
+	return entry, nil
+}
+
+// Replace the rule of an entry and save its file
+func (ls *LexiconStore) Update(language, id string, rule ReplacementRule) (LexiconEntry, error) {
+	if err := rule.Compile(); err != nil {
+		return LexiconEntry{}, invalidRequest(err)
+	}
+
+	return ls.modify(language, id, func(entries []LexiconEntry, i int) []LexiconEntry {
+		entries[i].ReplacementRule = rule
+		return entries
+	})
+}
+
+// Remove an entry and save its file
+func (ls *LexiconStore) Delete(language, id string) (LexiconEntry, error) {
+	return ls.modify(language, id, func(entries []LexiconEntry, i int) []LexiconEntry {
+		return append(entries[:i], entries[i+1:]...)
+	})
+}
+
+// Apply change to a copy of the entries of a language, keeping it only if the file could be saved.
+// Errors match ErrNotFound for unknown entries and ErrReadOnly for entries of other files.
+func (ls *LexiconStore) modify(language, id string, change func(entries []LexiconEntry, i int) []LexiconEntry) (LexiconEntry, error) {
+	key := LexiconKey(language)
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	previous := ls.entries[key]
+	for i, entry := range previous {
+		if entry.ID != id {
+			continue
+		}
+		if entry.ReadOnly {
+			return LexiconEntry{}, &kindError{kind: ErrReadOnly, err: fmt.Errorf("Entry comes from %s and can't be edited here", filepath.Base(entry.Source))}
+		}
+
+		entries := change(append([]LexiconEntry{}, previous...), i)
+		ls.entries[key] = entries
+		if err := ls.save(entry.Source); err != nil {
+			ls.entries[key] = previous
+			return LexiconEntry{}, err
+		}
+
+		if i < len(entries) && entries[i].ID == id {
+			entry = entries[i]
+		}
+		log.Printf("[LEXICON] ✏️  Changed entry %s of %s", id, key)
+		return entry, nil
+	}
+
+	return LexiconEntry{}, notFound(fmt.Errorf("Lexicon entry not found"))
+}
+
+// Write every entry that belongs to a JSON lexicon file. Must be called with ls.mu held.
+func (ls *LexiconStore) save(file string) error {
+	lexicon := lexiconFile{Entries: []lexiconFileEntry{}}
+	for _, entries := range ls.entries {
+		for _, entry := range entries {
+			if entry.Source == file {
+				lexicon.Entries = append(lexicon.Entries, lexiconFileEntry{ID: entry.ID, ReplacementRule: entry.ReplacementRule})
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(lexicon, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write next to the file and rename, so a failed write doesn't lose the lexicon
+	tempFile := file + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("Error saving lexicon: %v", err)
+	}
+	if err := os.Rename(tempFile, file); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("Error saving lexicon: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPLS = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="ipa" xml:lang="es">
+  <lexeme>
+    <grapheme>SQL</grapheme>
+    <alias>ese cu ele</alias>
+  </lexeme>
+  <lexeme>
+    <grapheme>wifi</grapheme>
+    <grapheme>WiFi</grapheme>
+    <alias>güifi</alias>
+  </lexeme>
+  <lexeme>
+    <grapheme>tomate</grapheme>
+    <phoneme>toˈmate</phoneme>
+  </lexeme>
+</lexicon>`
+
+func newTestLexiconStore(t *testing.T, files map[string]string) (*LexiconStore, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewLexiconStore()
+	store.Load([]string{dir})
+	return store, dir
+}
+
+func TestLexiconPLS(t *testing.T) {
+	store, _ := newTestLexiconStore(t, map[string]string{"lexicon.es.pls": testPLS})
+
+	entries := store.Entries("es")
+	want := []struct{ find, replace string }{{"SQL", "ese cu ele"}, {"wifi", "güifi"}, {"WiFi", "güifi"}}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d (the phoneme-only lexeme skipped)", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Find != want[i].find || entry.Replace != want[i].replace || !entry.ReadOnly || !entry.CaseSensitive || !entry.WholeWord {
+			t.Errorf("entry %d is %+v, want a read-only whole-word %q -> %q", i, entry, want[i].find, want[i].replace)
+		}
+	}
+
+	got := applyRules("El wifi y la WIFI con SQL, no SQLite.", store.Rules("es_MX"), "TEST")
+	if want := "El güifi y la WIFI con ese cu ele, no SQLite."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLexiconCRUD(t *testing.T) {
+	store, dir := newTestLexiconStore(t, map[string]string{"lexicon.es.pls": testPLS})
+
+	entry, err := store.Add("es", ReplacementRule{Find: `(\d+)\s?km\b`, Replace: "$1 kilómetros", Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Source != filepath.Join(dir, "lexicon.es.json") {
+		t.Errorf("entry saved to %s, want a new lexicon.es.json", entry.Source)
+	}
+	if got := applyRules("Son 5 km.", store.Rules("es"), "TEST"); got != "Son 5 kilómetros." {
+		t.Errorf("got %q after Add", got)
+	}
+
+	updated, err := store.Update("es", entry.ID, ReplacementRule{Find: "km", Replace: "kilómetros", WholeWord: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != entry.ID || updated.Find != "km" {
+		t.Errorf("got %+v after Update", updated)
+	}
+
+	if _, err := store.Delete("es", entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if entries := store.Entries("es"); len(entries) != 3 {
+		t.Errorf("got %d entries after Delete, want the 3 from the PLS file", len(entries))
+	}
+
+	// Unknown entries, read-only ones and invalid rules
+	readOnlyID := store.Entries("es")[0].ID
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"update missing", second(store.Update("es", entry.ID, ReplacementRule{Find: "x"})), ErrNotFound},
+		{"delete missing", second(store.Delete("es", entry.ID)), ErrNotFound},
+		{"missing language", second(store.Delete("en", readOnlyID)), ErrNotFound},
+		{"update PLS entry", second(store.Update("es", readOnlyID, ReplacementRule{Find: "x"})), ErrReadOnly},
+		{"delete PLS entry", second(store.Delete("es", readOnlyID)), ErrReadOnly},
+		{"invalid regex", second(store.Add("es", ReplacementRule{Find: "(", Regex: true})), ErrInvalidRequest},
+		{"empty find", second(store.Add("es", ReplacementRule{Replace: "x"})), ErrInvalidRequest},
+		{"no language", second(store.Add("", ReplacementRule{Find: "x"})), ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+	if entries := store.Entries("es"); len(entries) != 3 {
+		t.Errorf("failed changes left %d entries, want 3", len(entries))
+	}
+}
+
+func TestLexiconSurvivesReload(t *testing.T) {
+	store, dir := newTestLexiconStore(t, nil)
+
+	kept, err := store.Add("es", ReplacementRule{Find: "wifi", Replace: "güifi", WholeWord: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := store.Add("es", ReplacementRule{Find: "SQL", Replace: "ese cu ele", CaseSensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	regional, err := store.Add("es-MX", ReplacementRule{Find: "güifi", Replace: "guaifai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Delete("es", removed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewLexiconStore()
+	reloaded.Load([]string{dir})
+
+	entries := reloaded.Entries("es")
+	if len(entries) != 1 || entries[0].ID != kept.ID || entries[0].Replace != "güifi" || !entries[0].WholeWord || entries[0].ReadOnly {
+		t.Errorf("got %+v after reload, want only %+v", entries, kept)
+	}
+	if entries := reloaded.Entries("es_MX"); len(entries) != 1 || entries[0].ID != regional.ID {
+		t.Errorf("got %+v for es_MX after reload", entries)
+	}
+	if languages := reloaded.Languages(); len(languages) != 2 || languages["es"] != 1 || languages["es_mx"] != 1 {
+		t.Errorf("got languages %v", languages)
+	}
+
+	// The language's entries apply before the region's
+	if got := applyRules("Hay wifi.", reloaded.Rules("es_MX"), "TEST"); got != "Hay guaifai." {
+		t.Errorf("got %q with es_MX rules", got)
+	}
+	if got := applyRules("Hay wifi.", reloaded.Rules("es"), "TEST"); got != "Hay güifi." {
+		t.Errorf("got %q with es rules", got)
+	}
+}
+
+// The error of a store call, for tables of failures
+func second(_ LexiconEntry, err error) error {
+	return err
+}
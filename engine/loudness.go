@@ -0,0 +1,295 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Defaults for loudness normalization, following EBU R128 streaming practice
+const (
+	defaultTruePeakDb = -1.0
+	// Oversampling used to find peaks between samples (ITU-R BS.1770 annex 2)
+	truePeakOversampling = 4
+	limiterLookahead     = 5 * time.Millisecond
+	limiterRelease       = 50 * time.Millisecond
+)
+
+// Requested loudness normalization
+type LoudnessOptions struct {
+	TargetLUFS float64
+	TruePeakDb float64
+}
+
+// Measured loudness before and after normalization
+type LoudnessReport struct {
+	InputLUFS        float64 `json:"inputLufs"`
+	InputTruePeakDb  float64 `json:"inputTruePeakDb"`
+	GainDb           float64 `json:"gainDb"`
+	OutputLUFS       float64 `json:"outputLufs"`
+	OutputTruePeakDb float64 `json:"outputTruePeakDb"`
+}
+
+// Read loudness options from the convert settings ("target_lufs", "true_peak").
+// Returns nil when normalization wasn't requested.
+func parseLoudnessOptions(data map[string]interface{}) (*LoudnessOptions, error) {
+	target, ok := data["target_lufs"].(float64)
+	if !ok {
+		return nil, nil
+	}
+	if target < -70 || target > -5 {
+		return nil, fmt.Errorf("Loudness target (target_lufs) must be between -70 and -5 LUFS")
+	}
+
+	options := &LoudnessOptions{TargetLUFS: target, TruePeakDb: defaultTruePeakDb}
+	if truePeak, ok := data["true_peak"].(float64); ok {
+		if truePeak < -20 || truePeak > 0 {
+			return nil, fmt.Errorf("True peak ceiling (true_peak) must be between -20 and 0 dBTP")
+		}
+		options.TruePeakDb = truePeak
+	}
+	return options, nil
+}
+
+// Normalize a WAV file in place to the target integrated loudness,
+// limiting true peaks to the ceiling. Silent audio is left alone and gets no report.
+func normalizeLoudnessFile(wavPath string, options LoudnessOptions) (*LoudnessReport, error) {
+	buffer, header, err := ReadWAVFile(wavPath)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := samplesToFloat(buffer.Data, int(header.NumChannels), int(header.BitsPerSample))
+	rate := int(header.SampleRate)
+
+	inputLUFS := integratedLoudness(channels, rate)
+	if math.IsInf(inputLUFS, -1) {
+		// Nothing but silence; there is no level to normalize
+		log.Printf("[LOUDNESS] ⚠️  Audio is silent, skipping normalization")
+		return nil, nil
+	}
+
+	report := &LoudnessReport{
+		InputLUFS:       roundDb(inputLUFS),
+		InputTruePeakDb: roundDb(truePeakDb(channels, rate)),
+	}
+
+	gain := options.TargetLUFS - report.InputLUFS
+	report.GainDb = roundDb(gain)
+
+	linearGain := math.Pow(10, gain/20)
+	for _, channel := range channels {
+		for i := range channel {
+			channel[i] *= linearGain
+		}
+	}
+	limitTruePeak(channels, rate, math.Pow(10, options.TruePeakDb/20))
+
+	report.OutputLUFS = roundDb(integratedLoudness(channels, rate))
+	report.OutputTruePeakDb = roundDb(truePeakDb(channels, rate))
+
+	buffer.Data = floatToSamples(channels, int(header.BitsPerSample))
+	if err := writeWAVFile(wavPath, buffer, header); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[LOUDNESS] 🔊 %.1f LUFS -> %.1f LUFS (gain %+.1f dB, true peak %.1f dBTP)",
+		report.InputLUFS, report.OutputLUFS, report.GainDb, report.OutputTruePeakDb)
+	return report, nil
+}
+
+// Integrated loudness in LUFS per ITU-R BS.1770-4 / EBU R128: K-weighting,
+// 400 ms blocks with 75% overlap, an absolute gate at -70 LUFS and a relative gate 10 LU below.
+func integratedLoudness(channels [][]float64, sampleRate int) float64 {
+	if len(channels) == 0 || len(channels[0]) == 0 {
+		return math.Inf(-1)
+	}
+
+	frames := len(channels[0])
+	blockSize := sampleRate * 400 / 1000
+	step := sampleRate * 100 / 1000
+	if frames < blockSize {
+		// Shorter than one block: measure it as a whole
+		blockSize = frames
+	}
+
+	// Squared K-weighted samples summed over channels (all channel weights are 1 for mono and stereo)
+	power := make([]float64, frames)
+	for _, channel := range channels {
+		for i, value := range kWeight(channel, sampleRate) {
+			power[i] += value * value
+		}
+	}
+
+	// Running sums make every block a subtraction
+	cumulative := make([]float64, frames+1)
+	for i, value := range power {
+		cumulative[i+1] = cumulative[i] + value
+	}
+
+	blocks := []float64{}
+	for start := 0; start+blockSize <= frames; start += step {
+		meanSquare := (cumulative[start+blockSize] - cumulative[start]) / float64(blockSize)
+		if blockLoudness(meanSquare) > -70 {
+			blocks = append(blocks, meanSquare)
+		}
+	}
+	if len(blocks) == 0 {
+		return math.Inf(-1)
+	}
+
+	relativeGate := blockLoudness(meanOf(blocks)) - 10
+	gated := []float64{}
+	for _, block := range blocks {
+		if blockLoudness(block) > relativeGate {
+			gated = append(gated, block)
+		}
+	}
+
+	return blockLoudness(meanOf(gated))
+}
+
+func blockLoudness(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+func meanOf(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// Apply the BS.1770 K-weighting filter: a high shelf modelling the head,
+// followed by the RLB high-pass. Coefficients are derived for any sample rate.
+func kWeight(input []float64, sampleRate int) []float64 {
+	fs := float64(sampleRate)
+
+	// Stage 1: high shelf
+	k := math.Tan(math.Pi * 1681.974450955533 / fs)
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// Stage 2: high-pass
+	k = math.Tan(math.Pi * 38.13547087602444 / fs)
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return highPass.filter(shelf.filter(input))
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+func (bq biquad) filter(input []float64) []float64 {
+	output := make([]float64, len(input))
+	var x1, x2, y1, y2 float64
+	for i, x := range input {
+		y := bq.b0*x + bq.b1*x1 + bq.b2*x2 - bq.a1*y1 - bq.a2*y2
+		x2, x1 = x1, x
+		y2, y1 = y1, y
+		output[i] = y
+	}
+	return output
+}
+
+// Highest true peak over all channels, in dBTP
+func truePeakDb(channels [][]float64, sampleRate int) float64 {
+	peak := 0.0
+	for _, channel := range channels {
+		for _, value := range samplePeaks(channel, sampleRate) {
+			peak = math.Max(peak, value)
+		}
+	}
+	return 20 * math.Log10(peak)
+}
+
+// Peak level around each sample, including the peaks between samples that
+// show up once the signal is oversampled
+func samplePeaks(channel []float64, sampleRate int) []float64 {
+	oversampled := resampleSinc(channel, sampleRate, sampleRate*truePeakOversampling)
+
+	peaks := make([]float64, len(channel))
+	for i, value := range channel {
+		peak := math.Abs(value)
+		first := maxInt(i*truePeakOversampling-truePeakOversampling+1, 0)
+		last := minInt(i*truePeakOversampling+truePeakOversampling-1, len(oversampled)-1)
+		for j := first; j <= last; j++ {
+			peak = math.Max(peak, math.Abs(oversampled[j]))
+		}
+		peaks[i] = peak
+	}
+	return peaks
+}
+
+// Look-ahead limiter keeping true peaks at or below ceiling (linear).
+// Gain ramps down ahead of each peak and recovers with an exponential release;
+// all channels share one gain so the stereo image doesn't shift.
+func limitTruePeak(channels [][]float64, sampleRate int, ceiling float64) {
+	if len(channels) == 0 || len(channels[0]) == 0 {
+		return
+	}
+	frames := len(channels[0])
+
+	// Gain each sample needs on its own
+	gains := make([]float64, frames)
+	for i := range gains {
+		gains[i] = 1
+	}
+	limited := false
+	for _, channel := range channels {
+		for i, peak := range samplePeaks(channel, sampleRate) {
+			if peak > ceiling {
+				gains[i] = math.Min(gains[i], ceiling/peak)
+				limited = true
+			}
+		}
+	}
+	if !limited {
+		return
+	}
+
+	// Attack: ramp down linearly over the look-ahead so the gain is reached at the peak
+	lookahead := maxInt(int(limiterLookahead.Seconds()*float64(sampleRate)), 1)
+	attackStep := 1 / float64(lookahead)
+	for i := frames - 2; i >= 0; i-- {
+		gains[i] = math.Min(gains[i], gains[i+1]+attackStep)
+	}
+
+	// Release: recover towards unity without overshooting what later samples need
+	releaseCoef := 1 - math.Exp(-1/(limiterRelease.Seconds()*float64(sampleRate)))
+	for i := 1; i < frames; i++ {
+		released := gains[i-1] + (1-gains[i-1])*releaseCoef
+		gains[i] = math.Min(gains[i], released)
+	}
+
+	for _, channel := range channels {
+		for i := range channel {
+			channel[i] *= gains[i]
+		}
+	}
+}
+
+// Round to 0.01 dB for reports
+func roundDb(value float64) float64 {
+	return math.Round(value*100) / 100
+}
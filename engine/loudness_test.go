@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+func TestIntegratedLoudnessOfSine(t *testing.T) {
+	// EBU Tech 3341 case 1: a 1 kHz stereo sine at -23 dBFS reads -23 LUFS
+	amplitude := math.Pow(10, -23.0/20)
+	left := make([]float64, 20*48000)
+	for i := range left {
+		left[i] = amplitude * math.Sin(2*math.Pi*1000*float64(i)/48000)
+	}
+	right := append([]float64{}, left...)
+
+	if lufs := integratedLoudness([][]float64{left, right}, 48000); math.Abs(lufs+23) > 0.1 {
+		t.Errorf("stereo -23 dBFS sine measured %.2f LUFS, want -23", lufs)
+	}
+
+	// The same sine on one channel is 3 dB quieter
+	if lufs := integratedLoudness([][]float64{left}, 48000); math.Abs(lufs+26.01) > 0.1 {
+		t.Errorf("mono -23 dBFS sine measured %.2f LUFS, want -26", lufs)
+	}
+
+	if lufs := integratedLoudness([][]float64{make([]float64, 48000)}, 48000); !math.IsInf(lufs, -1) {
+		t.Errorf("silence measured %.2f LUFS", lufs)
+	}
+}
+
+func TestNormalizeLoudnessFile(t *testing.T) {
+	tests := []struct {
+		amplitude float64
+		target    float64
+	}{
+		{0.05, -16},
+		{0.8, -23},
+		{0.3, -20},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "speech.wav")
+		header := &WAVHeader{SampleRate: 22050, NumChannels: 1, BitsPerSample: 16}
+		buffer := &audio.IntBuffer{
+			Data:   sineSamples(440, 22050, 5*22050, tt.amplitude),
+			Format: &audio.Format{NumChannels: 1, SampleRate: 22050},
+		}
+		if err := writeWAVFile(path, buffer, header); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := normalizeLoudnessFile(path, LoudnessOptions{TargetLUFS: tt.target, TruePeakDb: defaultTruePeakDb})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Measure the written file again rather than trusting the report
+		normalized, normalizedHeader, err := ReadWAVFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		channels := samplesToFloat(normalized.Data, int(normalizedHeader.NumChannels), int(normalizedHeader.BitsPerSample))
+		lufs := integratedLoudness(channels, 22050)
+
+		if math.Abs(lufs-tt.target) > 1 {
+			t.Errorf("amplitude %.2f normalized to %.2f LUFS, want %.0f ±1", tt.amplitude, lufs, tt.target)
+		}
+		if math.Abs(report.OutputLUFS-lufs) > 0.1 {
+			t.Errorf("report says %.2f LUFS, the file measures %.2f", report.OutputLUFS, lufs)
+		}
+		if peak := truePeakDb(channels, 22050); peak > defaultTruePeakDb+0.1 {
+			t.Errorf("true peak %.2f dBTP is above the %.0f dBTP ceiling", peak, defaultTruePeakDb)
+		}
+	}
+}
+
+func TestNormalizeLoudnessLimitsPeaks(t *testing.T) {
+	// A sine can't reach -8 LUFS without peaks above -3 dBTP; the limiter holds them
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	header := &WAVHeader{SampleRate: 22050, NumChannels: 1, BitsPerSample: 16}
+	buffer := &audio.IntBuffer{Data: sineSamples(440, 22050, 3*22050, 0.1), Format: &audio.Format{NumChannels: 1, SampleRate: 22050}}
+	if err := writeWAVFile(path, buffer, header); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := normalizeLoudnessFile(path, LoudnessOptions{TargetLUFS: -8, TruePeakDb: -3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OutputTruePeakDb > -3+0.1 {
+		t.Errorf("true peak %.2f dBTP, want at most -3", report.OutputTruePeakDb)
+	}
+}
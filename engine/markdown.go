@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Block structure of markdown
+var (
+	markdownFencePattern   = regexp.MustCompile("^\\s*(```|~~~)")
+	markdownHeadingPattern = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(\s+#+)?\s*$`)
+	markdownSetextPattern  = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	markdownRulePattern    = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	markdownListPattern    = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	markdownTaskPattern    = regexp.MustCompile(`^\[[ xX]\]\s+`)
+	markdownQuotePattern   = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	markdownDefinitionLine = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	markdownTableSeparator = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	markdownCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// Marks that already end a heading or list item
+const markdownSentenceEndMarks = ".!?…:;"
+
+// Inline markup, applied in order
+var markdownInlineRules = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	// Images and footnote references have nothing to read
+	{regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`), ""},
+	{regexp.MustCompile(`\[\^[^\]]+\]`), ""},
+	// Links read their text only
+	{regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`), "$1"},
+	{regexp.MustCompile(`<((https?|ftp)://[^>\s]+|[^>\s@]+@[^>\s]+)>`), "$1"},
+	{regexp.MustCompile("`+([^`]+?)`+"), "$1"},
+	{regexp.MustCompile(`</?[a-zA-Z][^>]*>`), " "},
+	{regexp.MustCompile(`\*\*(.+?)\*\*`), "$1"},
+	{regexp.MustCompile(`__(.+?)__`), "$1"},
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},
+	{regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`), "$1"},
+	// Underscores only count at word edges, so snake_case survives
+	{regexp.MustCompile(`(^|[^\pL\pN_])_([^_\s](?:[^_]*[^_\s])?)_([^\pL\pN_]|$)`), "$1$2$3"},
+}
+
+// Backslash escapes, whose characters are read as they are rather than as markup
+var markdownEscapePattern = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|>~])")
+
+// Escaped characters are hidden in the private use area while the inline rules run
+const markdownEscapeBase = 0xE000
+
+// Turn markdown into segments to read: headings, paragraphs, list items and table rows
+// become sentences of their own. blockPause follows every heading, paragraph, list and table;
+// list items and table rows get a weak pause. Fenced code is skipped.
+func parseMarkdown(input string, model *Model, settings AudioSettings, blockPause time.Duration) []SSMLSegment {
+	input = markdownCommentPattern.ReplaceAllString(strings.ReplaceAll(input, "\r\n", "\n"), "")
+	lines := strings.Split(input, "\n")
+	itemPause := ssmlBreakStrengths["weak"]
+
+	segments := []SSMLSegment{}
+	// Lines of the paragraph or list item being read
+	buffer := []string{}
+
+	add := func(text string, pause time.Duration) {
+		text = markdownSentence(markdownInline(text))
+		if text == "" {
+			return
+		}
+		segments = append(segments, SSMLSegment{Text: text, Model: model, Settings: settings, Break: pause})
+	}
+
+	flush := func(pause time.Duration) {
+		if len(buffer) > 0 {
+			add(strings.Join(buffer, " "), pause)
+			buffer = buffer[:0]
+		}
+	}
+
+	endBlock := func() {
+		flush(0)
+		if len(segments) > 0 {
+			segments[len(segments)-1].Break = blockPause
+		}
+	}
+
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if match := markdownFencePattern.FindStringSubmatch(line); match != nil {
+			endBlock()
+			fence = match[1]
+			continue
+		}
+
+		line = markdownQuotePattern.ReplaceAllString(line, "")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			endBlock()
+
+		case markdownDefinitionLine.MatchString(line):
+			// Targets of reference links
+
+		case len(buffer) > 0 && markdownSetextPattern.MatchString(line):
+			// The paragraph so far was a heading underlined with === or ---
+			endBlock()
+
+		case markdownRulePattern.MatchString(line):
+			endBlock()
+
+		case markdownHeadingPattern.MatchString(line):
+			endBlock()
+			add(markdownHeadingPattern.FindStringSubmatch(line)[1], 0)
+			endBlock()
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "|") && markdownTableSeparator.MatchString(lines[i+1]):
+			endBlock()
+			header := markdownTableCells(line)
+			i += 2
+			for ; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				add(markdownTableRow(header, markdownTableCells(lines[i])), itemPause)
+			}
+			i--
+			endBlock()
+
+		case markdownListPattern.MatchString(line):
+			flush(itemPause)
+			item := markdownListPattern.FindStringSubmatch(line)[2]
+			buffer = append(buffer, markdownTaskPattern.ReplaceAllString(item, ""))
+
+		default:
+			buffer = append(buffer, trimmed)
+		}
+	}
+
+	flush(0)
+	if len(segments) > 0 {
+		// No pause after the end of the document
+		segments[len(segments)-1].Break = 0
+	}
+
+	log.Printf("[MARKDOWN] Parsed %d segments", len(segments))
+	return segments
+}
+
+// Strip inline markup, keeping the text that is meant to be read
+func markdownInline(text string) string {
+	text = markdownEscapePattern.ReplaceAllStringFunc(text, func(escape string) string {
+		return string(rune(markdownEscapeBase + int(escape[1])))
+	})
+	for _, rule := range markdownInlineRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replace)
+	}
+	text = strings.Map(func(r rune) rune {
+		if r >= markdownEscapeBase && r < markdownEscapeBase+utf8.RuneSelf {
+			return r - markdownEscapeBase
+		}
+		return r
+	}, text)
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// End text with a full stop unless it already ends a sentence, so piper
+// reads headings and list items with a falling tone
+func markdownSentence(text string) string {
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(text, "\"'”»)]"))
+	if text == "" || strings.ContainsRune(markdownSentenceEndMarks, last) {
+		return text
+	}
+	return text + "."
+}
+
+// Cells of a table row, without the outer pipes
+func markdownTableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	line = strings.ReplaceAll(line, `\|`, "\x00")
+
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(cell, "\x00", "|"))
+	}
+	return cells
+}
+
+// Read a table row as "header: value" pairs, so every value keeps its column name
+func markdownTableRow(header, cells []string) string {
+	parts := []string{}
+	for i, cell := range cells {
+		value := markdownInline(cell)
+		if value == "" {
+			continue
+		}
+		if i < len(header) {
+			if name := markdownInline(header[i]); name != "" && name != value {
+				value = name + ": " + value
+			}
+		}
+		parts = append(parts, value)
+	}
+	return strings.Join(parts, ", ")
+}
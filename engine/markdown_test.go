@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	const blockPause = 600 * time.Millisecond
+	itemPause := ssmlBreakStrengths["weak"]
+
+	type segment struct {
+		text  string
+		pause time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []segment
+	}{
+		{
+			name:  "headings",
+			input: "# Title #\n\nSome text here\nover two lines\n\nSub\n---\nMore text\n## Done?",
+			want: []segment{
+				{"Title.", blockPause},
+				{"Some text here over two lines.", blockPause},
+				{"Sub.", blockPause},
+				{"More text.", blockPause},
+				{"Done?", 0},
+			},
+		},
+		{
+			name:  "lists",
+			input: "Steps:\n- one\n* [x] two\n  continued\n1. three\n\nAfter.",
+			want: []segment{
+				{"Steps:", itemPause},
+				{"one.", itemPause},
+				{"two continued.", itemPause},
+				{"three.", blockPause},
+				{"After.", 0},
+			},
+		},
+		{
+			name:  "emphasis and links",
+			input: "**Bold** and *it*, _em_ but snake_case, ~~old~~ `code` [link](http://x.com) [ref][1]![img](a.png)[^2] <https://go.dev> &amp; \\*stars\\* \\_under\\_ 2\\*3\n\n[1]: http://example.com",
+			want: []segment{
+				{"Bold and it, em but snake_case, old code link ref https://go.dev & *stars* _under_ 2*3.", 0},
+			},
+		},
+		{
+			name:  "code fences are skipped",
+			input: "Before.\n```go\nfmt.Println(\"never read\")\n```\nAfter.\n~~~\nunclosed fences run to the end",
+			want: []segment{
+				{"Before.", blockPause},
+				{"After.", 0},
+			},
+		},
+		{
+			name:  "paragraph pauses, quotes and rules",
+			input: "> Quoted line\n> still quoted\n\n***\nFirst paragraph.\n\n\n\nSecond <!-- hidden --> paragraph",
+			want: []segment{
+				{"Quoted line still quoted.", blockPause},
+				{"First paragraph.", blockPause},
+				{"Second paragraph.", 0},
+			},
+		},
+		{
+			name:  "tables",
+			input: "| Name | Age |\n|:---|---:|\n| Ann | 30 |\n| Bob | |\n\nDone.",
+			want: []segment{
+				{"Name: Ann, Age: 30.", itemPause},
+				{"Name: Bob.", blockPause},
+				{"Done.", 0},
+			},
+		},
+		{
+			name:  "nothing to read",
+			input: "```\ncode only\n```\n\n---\n<!-- note -->",
+			want:  []segment{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := parseMarkdown(tt.input, nil, DefaultAudioSettings(), blockPause)
+			if len(segments) != len(tt.want) {
+				t.Fatalf("got %d segments %+v, want %d", len(segments), segments, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if segments[i].Text != want.text || segments[i].Break != want.pause {
+					t.Errorf("segment %d is %q with a %v pause, want %q with %v", i, segments[i].Text, segments[i].Break, want.text, want.pause)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Model struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Language     string            `json:"language"`
+	VoicePrompt  string            `json:"voiceprompt"`
+	JSONPath     string            `json:"jsonPath"`
+	OnnxPath     string            `json:"onnxPath"`
+	Image        string            `json:"image,omitempty"`
+	Replacements []ReplacementRule `json:"replacements"`
+	Source       string            `json:"source"`
+}
+
+type ModelCard struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	VoicePrompt string `json:"voiceprompt"`
+	Image       string `json:"image"`
+	// Each replacement is either a ["find", "replace"] pair or a ReplacementRule object
+	Replacements []json.RawMessage `json:"replacements"`
+}
+
+type ModelData struct {
+	ModelCard ModelCard `json:"modelcard"`
+}
+
+// Replacements read from ["find", "replace"] pairs are written back as pairs, the shape
+// clients of /models have always received; object-form rules are written as objects
+func (m Model) MarshalJSON() ([]byte, error) {
+	type model Model
+	replacements := make([]interface{}, len(m.Replacements))
+	for i, rule := range m.Replacements {
+		if rule.legacy {
+			replacements[i] = []string{rule.Find, rule.Replace}
+		} else {
+			replacements[i] = rule
+		}
+	}
+
+	return json.Marshal(struct {
+		model
+		Replacements []interface{} `json:"replacements"`
+	}{model(m), replacements})
+}
+
+// Scan the model paths for models and lexicons, replacing the ones found before
+func (e *Engine) Rescan() error {
+	log.Printf("[SCAN] 🔍 Starting model scan...")
+	modelPaths := e.ModelPaths()
+	models := []Model{}
+
+	for _, modelPath := range modelPaths {
+		log.Printf("[SCAN] 📁 Scanning directory: %s", modelPath)
+		
+		if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+			log.Printf("[SCAN] ❌ Model path does not exist: %s", modelPath)
+			continue
+		}
+
+		files, err := os.ReadDir(modelPath)
+		if err != nil {
+			log.Printf("[SCAN] ❌ Error reading directory %s: %v", modelPath, err)
+			continue
+		}
+
+		log.Printf("[SCAN] 📄 Found %d files in %s", len(files), filepath.Base(modelPath))
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			fileName := file.Name()
+			if !strings.HasSuffix(fileName, ".onnx.json") {
+				continue
+			}
+
+			jsonPath := filepath.Join(modelPath, fileName)
+			onnxPath := filepath.Join(modelPath, strings.TrimSuffix(fileName, ".json"))
+
+			// Check if corresponding .onnx file exists
+			if _, err := os.Stat(onnxPath); os.IsNotExist(err) {
+				log.Printf("[SCAN] ⚠️  Missing .onnx file for %s", fileName)
+				continue
+			}
+
+			// Read and parse model data
+			model, err := loadModel(jsonPath, onnxPath, modelPath)
+			if err != nil {
+				log.Printf("[SCAN] ❌ Error reading model %s: %v", fileName, err)
+				continue
+			}
+
+			models = append(models, model)
+			log.Printf("[SCAN] ✅ Found model: %s (%s) [%s]", model.Name, model.ID, model.Language)
+		}
+	}
+
+	log.Printf("[SCAN] 🎯 Total models found: %d", len(models))
+
+	e.mu.Lock()
+	e.models = models
+	e.mu.Unlock()
+
+	// Lexicons live next to the models
+	e.lexicons.Load(modelPaths)
+	return nil
+}
+
+// Models found by the last scan
+func (e *Engine) Models() []Model {
+	return append([]Model{}, e.modelList()...)
+}
+
+// The scanned models, shared. A rescan replaces the slice instead of changing it,
+// so pointers into it stay valid.
+func (e *Engine) modelList() []Model {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.models
+}
+
+// Directories scanned for models and lexicons
+func (e *Engine) ModelPaths() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]string{}, e.modelPaths...)
+}
+
+// Replace the directories scanned for models and rescan them
+func (e *Engine) SetModelPaths(paths []string) error {
+	e.mu.Lock()
+	e.modelPaths = append([]string{}, paths...)
+	e.mu.Unlock()
+	return e.Rescan()
+}
+
+func loadModel(jsonPath, onnxPath, source string) (Model, error) {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return Model{}, err
+	}
+
+	var modelData ModelData
+	if err := json.Unmarshal(data, &modelData); err != nil {
+		return Model{}, err
+	}
+
+	mc := modelData.ModelCard
+	
+	// Extract base64 image if it exists
+	var imageBase64 string
+	if mc.Image != "" {
+		imageBase64 = processImageData(mc.Image)
+	}
+
+	// Process replacements
+	replacements := parseReplacements(mc.Replacements, filepath.Base(jsonPath))
+	if len(mc.Replacements) == 0 {
+		// Default replacements
+		replacements = []ReplacementRule{
+			legacyReplacement("\n", " . "),
+			legacyReplacement("*", ""),
+			legacyReplacement(")", ","),
+		}
+	}
+
+	// Get model ID from filename if not in modelcard
+	modelID := mc.ID
+	if modelID == "" {
+		modelID = strings.TrimSuffix(filepath.Base(jsonPath), ".onnx.json")
+	}
+
+	// Get model name
+	modelName := mc.Name
+	if modelName == "" {
+		modelName = modelID
+	}
+
+	model := Model{
+		ID:           modelID,
+		Name:         modelName,
+		Description:  getOrDefault(mc.Description, "No description available"),
+		Language:     getOrDefault(mc.Language, "Unknown"),
+		VoicePrompt:  getOrDefault(mc.VoicePrompt, "Not available"),
+		JSONPath:     jsonPath,
+		OnnxPath:     onnxPath,
+		Image:        imageBase64,
+		Replacements: replacements,
+		Source:       source,
+	}
+
+	return model, nil
+}
+
+// Read and compile the replacements of a model card, skipping (and logging) invalid ones
+func parseReplacements(raw []json.RawMessage, fileName string) []ReplacementRule {
+	rules := []ReplacementRule{}
+
+	for i, data := range raw {
+		var rule ReplacementRule
+		var pair []string
+		if err := json.Unmarshal(data, &pair); err == nil {
+			if len(pair) >= 2 && pair[0] != "" {
+				rules = append(rules, legacyReplacement(pair[0], pair[1]))
+			}
+			continue
+		}
+
+		if err := json.Unmarshal(data, &rule); err != nil {
+			log.Printf("[SCAN] ⚠️  Invalid replacement %d in %s: %v", i+1, fileName, err)
+			continue
+		}
+
+		if err := rule.Compile(); err != nil {
+			log.Printf("[SCAN] ⚠️  Invalid replacement %d in %s: %v", i+1, fileName, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func processImageData(imageData string) string {
+	// Extract base64 data from data URI
+	if strings.Contains(imageData, "base64,") {
+		parts := strings.SplitN(imageData, "base64,", 2)
+		if len(parts) == 2 {
+			return parts[1]
+		}
+	}
+	return imageData
+}
+
+func getOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// Find a model by the path of its .onnx file
+func (e *Engine) FindModelByPath(onnxPath string) (*Model, error) {
+	models := e.modelList()
+	for i := range models {
+		if models[i].OnnxPath == onnxPath {
+			return &models[i], nil
+		}
+	}
+	return nil, fmt.Errorf("model not found")
+}
+
+// Find a model by its ID, falling back to a case-insensitive match on ID or name
+func (e *Engine) FindModelByID(id string) (*Model, error) {
+	models := e.modelList()
+	for i := range models {
+		if models[i].ID == id {
+			return &models[i], nil
+		}
+	}
+	for i := range models {
+		if strings.EqualFold(models[i].ID, id) || strings.EqualFold(models[i].Name, id) {
+			return &models[i], nil
+		}
+	}
+	return nil, fmt.Errorf("model not found")
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseReplacements(t *testing.T) {
+	var raw []json.RawMessage
+	err := json.Unmarshal([]byte(`[
+		["Dr.", "Doctor"],
+		["km", "kilometers"],
+		["", "ignored"],
+		["only find"],
+		{"find": "(\\d+)\\s?kg\\b", "replace": "$1 kilograms", "regex": true},
+		{"find": "wifi", "replace": "why fi", "wholeWord": true},
+		{"find": "US", "replace": "U S", "caseSensitive": true, "wholeWord": true},
+		{"find": "(unclosed", "replace": "x", "regex": true},
+		{"find": "", "replace": "x"},
+		42
+	]`), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := parseReplacements(raw, "test.onnx.json")
+	if len(rules) != 5 {
+		t.Fatalf("got %d rules, want the 5 valid ones", len(rules))
+	}
+	if !rules[0].legacy || !rules[1].legacy || rules[2].legacy || !rules[2].Regex {
+		t.Errorf("got %+v", rules)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		// Pairs ignore case and match whole words, or at the start for abbreviations
+		{"dr. Smith and DR. Jones", "Doctor Smith and Doctor Jones"},
+		{"5 km, 5 KM, 5km and kms", "5 kilometers, 5 kilometers, 5km and kms"},
+		// Regex rules expand groups
+		{"It weighs 3kg or 4 kg.", "It weighs 3 kilograms or 4 kilograms."},
+		// wholeWord skips words glued to letters or digits; matching ignores case unless set
+		{"WiFi, wifi2 and wifis", "why fi, wifi2 and wifis"},
+		{"The US bus uses US-made parts", "The U S bus uses U S-made parts"},
+	}
+	for _, tt := range tests {
+		if got := applyReplacements(tt.text, rules); got != tt.want {
+			t.Errorf("applyReplacements(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidRegexRule(t *testing.T) {
+	rule := ReplacementRule{Find: "(unclosed", Replace: "x", Regex: true}
+	if err := rule.Compile(); err == nil {
+		t.Fatal("compiled an invalid regex")
+	}
+
+	// The same text is fine as a literal
+	rule.Regex = false
+	if err := rule.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	if got, count := rule.apply("an (unclosed paren"); got != "an x paren" || count != 1 {
+		t.Errorf("got %q with %d replacements", got, count)
+	}
+}
+
+func TestModelJSONKeepsReplacementPairs(t *testing.T) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(`[["Dr.", "Doctor"], {"find": "\\d+ km", "replace": "some kilometers", "regex": true}]`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	model := Model{ID: "en_US-test", Name: "Test", Replacements: parseReplacements(raw, "test.onnx.json")}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		ID           string            `json:"id"`
+		Name         string            `json:"name"`
+		Replacements []json.RawMessage `json:"replacements"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != "en_US-test" || decoded.Name != "Test" || len(decoded.Replacements) != 2 {
+		t.Fatalf("got %s", data)
+	}
+	if got := string(decoded.Replacements[0]); got != `["Dr.","Doctor"]` {
+		t.Errorf("pair written as %s", got)
+	}
+	if got := string(decoded.Replacements[1]); got != `{"find":"\\d+ km","replace":"some kilometers","regex":true}` {
+		t.Errorf("object rule written as %s", got)
+	}
+}
@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Piper fixes the synthesis parameters at startup, so a worker can only be
+// reused for requests with the same model and scales. The speaker is sent per line.
+type workerKey struct {
+	ModelPath   string
+	NoiseScale  float64
+	LengthScale float64
+	NoiseW      float64
+}
+
+// A long-lived piper process in --json-input mode that keeps its model loaded
+type PiperWorker struct {
+	key      workerKey
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	stderr   *tailBuffer
+	exited   chan struct{}
+	lastUsed time.Time
+}
+
+type WorkerPool struct {
+	idle  map[workerKey][]*PiperWorker
+	busy  int
+	total int
+	mu    sync.Mutex
+
+	// Builds the piper command for a worker
+	command func(args ...string) *exec.Cmd
+	// Most processes to keep running, the queue's concurrency limit
+	maxWorkers func() int
+	// Workers that haven't synthesized anything for this long are stopped
+	idleTimeout time.Duration
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+func NewWorkerPool(command func(args ...string) *exec.Cmd, maxWorkers func() int, idleTimeout time.Duration) *WorkerPool {
+	wp := &WorkerPool{
+		idle:        make(map[workerKey][]*PiperWorker),
+		command:     command,
+		maxWorkers:  maxWorkers,
+		idleTimeout: idleTimeout,
+		shutdown:    make(chan struct{}),
+	}
+
+	go wp.janitor()
+	return wp
+}
+
+// Synthesize text with a pooled worker and return the generated WAV file.
+// Cancelling ctx kills the worker, which can't be interrupted in the middle of a sentence.
+func (wp *WorkerPool) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	key := workerKey{
+		ModelPath:   modelPath,
+		NoiseScale:  settings.NoiseScale,
+		LengthScale: settings.LengthScale,
+		NoiseW:      settings.NoiseW,
+	}
+
+	worker, err := wp.acquire(key)
+	if err != nil {
+		return "", err
+	}
+
+	stopKill := context.AfterFunc(ctx, func() {
+		worker.cmd.Process.Kill()
+	})
+	outputFile, err := worker.synthesize(text, settings.Speaker)
+	if !stopKill() {
+		// Killed, maybe after the sentence was already written
+		log.Printf("[WORKERS] 🛑 Killed worker for %s: cancelled", filepath.Base(modelPath))
+		wp.discard(worker)
+		if err == nil {
+			os.Remove(outputFile)
+		}
+		return "", &killedRunError{Err: ctx.Err(), Stderr: worker.stderr.String()}
+	}
+	if err != nil {
+		// Never hand a worker in an unknown state to the next request
+		wp.discard(worker)
+		return "", err
+	}
+
+	wp.release(worker)
+	return outputFile, nil
+}
+
+// Take an idle worker for key, starting a new one if needed.
+// The total number of processes is kept within the queue's concurrency limit.
+func (wp *WorkerPool) acquire(key workerKey) (*PiperWorker, error) {
+	wp.mu.Lock()
+
+	if workers := wp.idle[key]; len(workers) > 0 {
+		worker := workers[len(workers)-1]
+		wp.idle[key] = workers[:len(workers)-1]
+		wp.busy++
+		wp.mu.Unlock()
+		return worker, nil
+	}
+
+	// Make room by stopping the least recently used idle worker of another model
+	if wp.total >= wp.maxWorkers() {
+		if oldest := wp.popOldestIdle(); oldest != nil {
+			go oldest.stop()
+		}
+	}
+
+	wp.total++
+	wp.busy++
+	wp.mu.Unlock()
+
+	worker, err := wp.startWorker(key)
+	if err != nil {
+		wp.mu.Lock()
+		wp.total--
+		wp.busy--
+		wp.mu.Unlock()
+		return nil, err
+	}
+
+	return worker, nil
+}
+
+func (wp *WorkerPool) release(worker *PiperWorker) {
+	worker.lastUsed = time.Now()
+
+	wp.mu.Lock()
+	wp.idle[worker.key] = append(wp.idle[worker.key], worker)
+	wp.busy--
+	wp.mu.Unlock()
+}
+
+func (wp *WorkerPool) discard(worker *PiperWorker) {
+	wp.mu.Lock()
+	wp.total--
+	wp.busy--
+	wp.mu.Unlock()
+
+	worker.stop()
+}
+
+// popOldestIdle must be called with wp.mu held
+func (wp *WorkerPool) popOldestIdle() *PiperWorker {
+	var oldest *PiperWorker
+	var oldestKey workerKey
+	oldestIndex := -1
+
+	for key, workers := range wp.idle {
+		for i, worker := range workers {
+			if oldest == nil || worker.lastUsed.Before(oldest.lastUsed) {
+				oldest, oldestKey, oldestIndex = worker, key, i
+			}
+		}
+	}
+
+	if oldest == nil {
+		return nil
+	}
+
+	workers := wp.idle[oldestKey]
+	wp.idle[oldestKey] = append(workers[:oldestIndex], workers[oldestIndex+1:]...)
+	if len(wp.idle[oldestKey]) == 0 {
+		delete(wp.idle, oldestKey)
+	}
+	wp.total--
+	return oldest
+}
+
+// Stop workers that have been idle too long or exceed the concurrency limit
+func (wp *WorkerPool) janitor() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			wp.evictIdle()
+		case <-wp.shutdown:
+			return
+		}
+	}
+}
+
+func (wp *WorkerPool) evictIdle() {
+	maxConcurrent := wp.maxWorkers()
+	evicted := []*PiperWorker{}
+
+	wp.mu.Lock()
+	for key, workers := range wp.idle {
+		kept := workers[:0]
+		for _, worker := range workers {
+			if time.Since(worker.lastUsed) > wp.idleTimeout {
+				evicted = append(evicted, worker)
+				wp.total--
+			} else {
+				kept = append(kept, worker)
+			}
+		}
+		if len(kept) == 0 {
+			delete(wp.idle, key)
+		} else {
+			wp.idle[key] = kept
+		}
+	}
+	for wp.total > maxConcurrent {
+		oldest := wp.popOldestIdle()
+		if oldest == nil {
+			break
+		}
+		evicted = append(evicted, oldest)
+	}
+	wp.mu.Unlock()
+
+	for _, worker := range evicted {
+		log.Printf("[WORKERS] 💤 Stopping idle worker for %s", filepath.Base(worker.key.ModelPath))
+		worker.stop()
+	}
+}
+
+// Number of running worker processes and how many are synthesizing right now
+func (wp *WorkerPool) Stats() (total, busy int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.total, wp.busy
+}
+
+// Stop every idle worker
+func (wp *WorkerPool) Close() {
+	wp.mu.Lock()
+	workers := []*PiperWorker{}
+	for _, idle := range wp.idle {
+		workers = append(workers, idle...)
+	}
+	wp.idle = make(map[workerKey][]*PiperWorker)
+	wp.total -= len(workers)
+	wp.mu.Unlock()
+
+	for _, worker := range workers {
+		worker.stop()
+	}
+}
+
+// Stop every idle worker and the janitor, when the pool is no longer needed
+func (wp *WorkerPool) Shutdown() {
+	wp.shutdownOnce.Do(func() { close(wp.shutdown) })
+	wp.Close()
+}
+
+func (wp *WorkerPool) startWorker(key workerKey) (*PiperWorker, error) {
+	args := []string{
+		"-m", key.ModelPath,
+		"--json-input",
+		"--output_dir", os.TempDir(),
+		"--noise-scale", fmt.Sprintf("%.3f", key.NoiseScale),
+		"--length-scale", fmt.Sprintf("%.3f", key.LengthScale),
+		"--noise-w", fmt.Sprintf("%.3f", key.NoiseW),
+	}
+
+	cmd := wp.command(args...)
+	log.Printf("[WORKERS] 🚀 Starting worker: %s %v", cmd.Path, args)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating stdout pipe: %v", err)
+	}
+
+	stderr := &tailBuffer{max: 4096}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting piper worker: %v", err)
+	}
+
+	worker := &PiperWorker{
+		key:      key,
+		cmd:      cmd,
+		stdin:    stdin,
+		stdout:   bufio.NewReader(stdout),
+		stderr:   stderr,
+		exited:   make(chan struct{}),
+		lastUsed: time.Now(),
+	}
+
+	go func() {
+		cmd.Wait()
+		close(worker.exited)
+	}()
+
+	return worker, nil
+}
+
+// Send one sentence to the worker and wait for the path of the WAV it wrote
+func (pw *PiperWorker) synthesize(text string, speaker int) (string, error) {
+	outputFile := filepath.Join(os.TempDir(), fmt.Sprintf("tts_%s.wav", generateRandomString(8)))
+
+	line, err := json.Marshal(map[string]interface{}{
+		"text":        text,
+		"speaker_id":  speaker,
+		"output_file": outputFile,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("[WORKERS] Input text: %s", text)
+
+	if _, err := pw.stdin.Write(append(line, '\n')); err != nil {
+		return "", &PiperError{Err: fmt.Errorf("error writing to worker: %v", err), Stderr: pw.stderr.String()}
+	}
+
+	// Piper prints the path of each file it writes
+	writtenPath, err := pw.stdout.ReadString('\n')
+	if err != nil {
+		os.Remove(outputFile)
+		// Stderr is only complete once the process has been waited for
+		select {
+		case <-pw.exited:
+		case <-time.After(time.Second):
+		}
+		return "", &PiperError{Err: fmt.Errorf("worker exited: %v", err), Stderr: pw.stderr.String()}
+	}
+	writtenPath = strings.TrimSpace(writtenPath)
+
+	if _, err := os.Stat(writtenPath); err != nil {
+		return "", &PiperError{Err: fmt.Errorf("output file not created: %s", writtenPath), Stderr: pw.stderr.String()}
+	}
+
+	return writtenPath, nil
+}
+
+func (pw *PiperWorker) stop() {
+	pw.stdin.Close()
+
+	select {
+	case <-pw.exited:
+	case <-time.After(2 * time.Second):
+		pw.cmd.Process.Kill()
+		<-pw.exited
+	}
+}
+
+// tailBuffer keeps the last max bytes written to it, for error reports
+type tailBuffer struct {
+	data []byte
+	max  int
+	mu   sync.Mutex
+}
+
+func (tb *tailBuffer) Write(p []byte) (int, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.data = append(tb.data, p...)
+	if len(tb.data) > tb.max {
+		tb.data = tb.data[len(tb.data)-tb.max:]
+	}
+	return len(p), nil
+}
+
+func (tb *tailBuffer) String() string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return string(tb.data)
+}
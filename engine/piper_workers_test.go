@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Runs this test binary as a stand-in for piper in --json-input mode, see TestHelperWorker
+func fakeWorkerCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperWorker", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), "GOPIPER_HELPER_WORKER=1")
+	return cmd
+}
+
+// Not a real test: answers like piper does, writing a file per line of JSON.
+// "crash" makes it exit with an error and "hang" makes it stop answering.
+func TestHelperWorker(t *testing.T) {
+	if os.Getenv("GOPIPER_HELPER_WORKER") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var line struct {
+			Text       string `json:"text"`
+			OutputFile string `json:"output_file"`
+		}
+		json.Unmarshal(scanner.Bytes(), &line)
+
+		switch line.Text {
+		case "crash":
+			fmt.Fprintln(os.Stderr, "[error] model exploded")
+			os.Exit(1)
+		case "hang":
+			time.Sleep(time.Minute)
+		}
+
+		os.WriteFile(line.OutputFile, []byte("RIFF"), 0644)
+		fmt.Println(line.OutputFile)
+	}
+	os.Exit(0)
+}
+
+func newTestWorkerPool(maxWorkers int) *WorkerPool {
+	return NewWorkerPool(fakeWorkerCommand, func() int { return maxWorkers }, time.Minute)
+}
+
+func synthesizeWithPool(t *testing.T, wp *WorkerPool, text string, settings AudioSettings) {
+	t.Helper()
+
+	outputFile, err := wp.Synthesize(context.Background(), text, "voice.onnx", settings)
+	if err != nil {
+		t.Fatalf("Synthesize(%q): %v", text, err)
+	}
+	if _, err := os.Stat(outputFile); err != nil {
+		t.Fatalf("output of %q missing: %v", text, err)
+	}
+	os.Remove(outputFile)
+}
+
+func TestWorkerPoolReusesWorkers(t *testing.T) {
+	wp := newTestWorkerPool(4)
+	defer wp.Shutdown()
+
+	for i := 0; i < 3; i++ {
+		synthesizeWithPool(t, wp, fmt.Sprintf("Sentence %d.", i), DefaultAudioSettings())
+	}
+
+	if total, busy := wp.Stats(); total != 1 || busy != 0 {
+		t.Errorf("after three sentences with the same settings: total %d, busy %d; want 1, 0", total, busy)
+	}
+
+	// The speaker is sent per line, so it doesn't need another worker
+	settings := DefaultAudioSettings()
+	settings.Speaker = 3
+	synthesizeWithPool(t, wp, "Another speaker.", settings)
+	if total, _ := wp.Stats(); total != 1 {
+		t.Errorf("after changing the speaker: total %d, want 1", total)
+	}
+
+	// The scales are fixed when piper starts
+	settings.LengthScale = 1.5
+	synthesizeWithPool(t, wp, "Slower.", settings)
+	if total, _ := wp.Stats(); total != 2 {
+		t.Errorf("after changing the length scale: total %d, want 2", total)
+	}
+
+	wp.Close()
+	if total, _ := wp.Stats(); total != 0 {
+		t.Errorf("after Close: total %d, want 0", total)
+	}
+}
+
+func TestWorkerPoolStaysWithinLimit(t *testing.T) {
+	wp := newTestWorkerPool(1)
+	defer wp.Shutdown()
+
+	settings := DefaultAudioSettings()
+	for i := 0; i < 3; i++ {
+		settings.NoiseScale = 0.5 + float64(i)*0.1
+		synthesizeWithPool(t, wp, "Hello.", settings)
+
+		if total, _ := wp.Stats(); total != 1 {
+			t.Fatalf("with a limit of 1 and %d models: total %d", i+1, total)
+		}
+	}
+}
+
+func TestWorkerPoolDiscardsFailedWorker(t *testing.T) {
+	wp := newTestWorkerPool(4)
+	defer wp.Shutdown()
+
+	synthesizeWithPool(t, wp, "Warm up.", DefaultAudioSettings())
+
+	_, err := wp.Synthesize(context.Background(), "crash", "voice.onnx", DefaultAudioSettings())
+	var piperErr *PiperError
+	if !errors.As(err, &piperErr) {
+		t.Fatalf("crashed worker: got %v, want a *PiperError", err)
+	}
+	if !strings.Contains(piperErr.Stderr, "model exploded") {
+		t.Errorf("stderr %q doesn't include what piper printed", piperErr.Stderr)
+	}
+	if total, busy := wp.Stats(); total != 0 || busy != 0 {
+		t.Errorf("after the crash: total %d, busy %d; want 0, 0", total, busy)
+	}
+
+	// The next sentence starts a fresh worker
+	synthesizeWithPool(t, wp, "Still working.", DefaultAudioSettings())
+}
+
+func TestWorkerPoolKillsWorkerOnCancel(t *testing.T) {
+	wp := newTestWorkerPool(4)
+	defer wp.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := wp.Synthesize(ctx, "hang", "voice.onnx", DefaultAudioSettings())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v to give up on a hung worker", elapsed)
+	}
+	if total, busy := wp.Stats(); total != 0 || busy != 0 {
+		t.Errorf("after cancelling: total %d, busy %d; want 0, 0", total, busy)
+	}
+}
@@ -0,0 +1,71 @@
+package engine
+
+import "context"
+
+// Priority decides which queued sentences get a piper process first
+type Priority int
+
+const (
+	// Someone is waiting for the audio, like a /convert request or a stream. The default.
+	PriorityInteractive Priority = iota
+	// Background work like jobs and audiobooks. Runs after interactive sentences, but still gets
+	// one start in every five while interactive ones are queued, so it always makes progress.
+	PriorityBatch
+
+	priorityCount
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	default:
+		return "unknown"
+	}
+}
+
+type queueContextKey int
+
+const (
+	priorityContextKey queueContextKey = iota
+	clientContextKey
+	startedContextKey
+)
+
+// Queue the sentences of conversions run with the returned context at priority p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey, p)
+}
+
+// Attribute the sentences of conversions run with the returned context to client, such as
+// an API key or address. Within a priority the queue takes one sentence from each client
+// in turn, so a long text doesn't hold up everybody else's. Work without a client shares one turn.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientContextKey, client)
+}
+
+// Call started whenever a sentence of conversions run with the returned context leaves the
+// queue and starts running, e.g. to tell work that is waiting from work in progress.
+// It runs on the queue's goroutine, so it must be quick.
+func WithStartHook(ctx context.Context, started func()) context.Context {
+	return context.WithValue(ctx, startedContextKey, started)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityContextKey).(Priority); ok && p >= 0 && p < priorityCount {
+		return p
+	}
+	return PriorityInteractive
+}
+
+func clientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientContextKey).(string)
+	return client
+}
+
+func startHookFromContext(ctx context.Context) func() {
+	started, _ := ctx.Value(startedContextKey).(func())
+	return started
+}
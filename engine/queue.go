@@ -0,0 +1,324 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"runtime"
+	"sync"
+)
+
+type ProcessQueue struct {
+	MaxConcurrent int
+	running       map[string]bool
+	// Queued tasks by priority; within each, clients take turns
+	queues [priorityCount]*fairQueue
+	// Interactive tasks started in a row while batch work was waiting
+	interactiveStreak int
+	mu                sync.Mutex
+	cpuCores          int
+}
+
+type QueueItem struct {
+	Task     func(ctx context.Context) (interface{}, error)
+	Result   chan TaskResult
+	ID       string
+	Priority Priority
+	Client   string
+	// Cancelling it drops the item from the queue, or is left to the task once running
+	ctx context.Context
+}
+
+type TaskResult struct {
+	Data  interface{}
+	Error error
+}
+
+type QueueStatus struct {
+	MaxConcurrent int `json:"maxConcurrent"`
+	Running       int `json:"running"`
+	Queued        int `json:"queued"`
+	// Queued tasks of each priority, and how many clients they belong to
+	QueuedByPriority map[string]int `json:"queuedByPriority"`
+	QueuedClients    int            `json:"queuedClients"`
+	CPUCores         int            `json:"cpuCores"`
+	CacheHits        int64          `json:"cacheHits"`
+	CacheMisses      int64          `json:"cacheMisses"`
+	Cache            *CacheStats    `json:"cache,omitempty"`
+}
+
+func NewProcessQueue(maxConcurrent int) *ProcessQueue {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	if maxConcurrent > 32 {
+		maxConcurrent = 32
+	}
+
+	pq := &ProcessQueue{
+		MaxConcurrent: maxConcurrent,
+		running:       make(map[string]bool),
+		cpuCores:      runtime.NumCPU(),
+	}
+	for i := range pq.queues {
+		pq.queues[i] = newFairQueue()
+	}
+
+	log.Printf("[QUEUE] Initialized with max %d concurrent processes (CPU cores: %d)", maxConcurrent, pq.cpuCores)
+	return pq
+}
+
+func (pq *ProcessQueue) SetMaxConcurrent(max int) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if max < 1 {
+		max = 1
+	}
+	if max > 32 {
+		max = 32
+	}
+
+	pq.MaxConcurrent = max
+	log.Printf("[QUEUE] Max concurrent processes updated to %d", pq.MaxConcurrent)
+	
+	// Process queue after updating limit
+	go pq.processQueue()
+}
+
+// Queue a task and wait for its result. If ctx is cancelled while the task is still
+// queued it is dropped; a running task gets ctx and is expected to stop on its own.
+// The priority and client set on ctx (see WithPriority and WithClient) decide when it runs.
+func (pq *ProcessQueue) Add(ctx context.Context, task func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resultChan := make(chan TaskResult, 1)
+	
+	id := generateRandomID()
+	
+	queueItem := QueueItem{
+		Task:     task,
+		Result:   resultChan,
+		ID:       id,
+		Priority: priorityFromContext(ctx),
+		Client:   clientFromContext(ctx),
+		ctx:      ctx,
+	}
+
+	pq.mu.Lock()
+	pq.queues[queueItem.Priority].push(queueItem)
+	queueSize := pq.queuedCount()
+	runningSize := len(pq.running)
+	pq.mu.Unlock()
+
+	log.Printf("[QUEUE] Added %s task %s to queue. Queue size: %d, Running: %d", queueItem.Priority, id, queueSize, runningSize)
+
+	// Try to process queue
+	go pq.processQueue()
+
+	// Wait for result
+	select {
+	case result := <-resultChan:
+		return result.Data, result.Error
+	case <-ctx.Done():
+		if pq.remove(queueItem) {
+			log.Printf("[QUEUE] Dropped cancelled task %s", id)
+			return nil, ctx.Err()
+		}
+		// Already running, wait for the task to notice
+		result := <-resultChan
+		return result.Data, result.Error
+	}
+}
+
+// Take a task out of the queue if it hasn't started yet
+func (pq *ProcessQueue) remove(item QueueItem) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	return pq.queues[item.Priority].remove(item.Client, item.ID)
+}
+
+// Interactive tasks that may start in a row while batch work waits, before a batch task gets a turn
+const interactivePerBatch = 4
+
+// Take the next task to run: interactive tasks go before batch ones, except that every
+// interactivePerBatch+1th start goes to batch work so it is never starved.
+// Within a priority clients take turns. Must be called with pq.mu held.
+func (pq *ProcessQueue) next() (QueueItem, bool) {
+	batch := pq.queues[PriorityBatch]
+	if pq.interactiveStreak >= interactivePerBatch && batch.size > 0 {
+		pq.interactiveStreak = 0
+		return batch.pop()
+	}
+
+	for priority, fq := range pq.queues {
+		if item, ok := fq.pop(); ok {
+			if Priority(priority) == PriorityInteractive && batch.size > 0 {
+				pq.interactiveStreak++
+			} else {
+				pq.interactiveStreak = 0
+			}
+			return item, true
+		}
+	}
+	return QueueItem{}, false
+}
+
+// queuedCount must be called with pq.mu held
+func (pq *ProcessQueue) queuedCount() int {
+	count := 0
+	for _, fq := range pq.queues {
+		count += fq.size
+	}
+	return count
+}
+
+func (pq *ProcessQueue) processQueue() {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	for len(pq.running) < pq.MaxConcurrent {
+		queueItem, ok := pq.next()
+		if !ok {
+			break
+		}
+
+		pq.running[queueItem.ID] = true
+
+		log.Printf("[QUEUE] Starting task %s. Running: %d/%d", queueItem.ID, len(pq.running), pq.MaxConcurrent)
+
+		go func(item QueueItem) {
+			if started := startHookFromContext(item.ctx); started != nil {
+				started()
+			}
+
+			// Execute task
+			data, err := item.Task(item.ctx)
+
+			// Send result
+			item.Result <- TaskResult{Data: data, Error: err}
+			close(item.Result)
+
+			// Remove from running
+			pq.mu.Lock()
+			delete(pq.running, item.ID)
+			runningSize := len(pq.running)
+			pq.mu.Unlock()
+
+			if err != nil {
+				log.Printf("[QUEUE] Failed task %s. Running: %d/%d", item.ID, runningSize, pq.MaxConcurrent)
+			} else {
+				log.Printf("[QUEUE] Completed task %s. Running: %d/%d", item.ID, runningSize, pq.MaxConcurrent)
+			}
+
+			// Process next item in queue
+			go pq.processQueue()
+		}(queueItem)
+	}
+}
+
+func (pq *ProcessQueue) GetStatus() QueueStatus {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	status := QueueStatus{
+		MaxConcurrent:    pq.MaxConcurrent,
+		Running:          len(pq.running),
+		Queued:           pq.queuedCount(),
+		QueuedByPriority: make(map[string]int),
+		CPUCores:         pq.cpuCores,
+	}
+
+	clients := make(map[string]bool)
+	for priority, fq := range pq.queues {
+		status.QueuedByPriority[Priority(priority).String()] = fq.size
+		for _, client := range fq.clients {
+			clients[client] = true
+		}
+	}
+	status.QueuedClients = len(clients)
+
+	return status
+}
+
+// Queued tasks of one priority, taken from each client in turn
+type fairQueue struct {
+	// Clients with queued tasks, in the order of their next turn
+	clients []string
+	tasks   map[string][]QueueItem
+	size    int
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{tasks: make(map[string][]QueueItem)}
+}
+
+func (fq *fairQueue) push(item QueueItem) {
+	if len(fq.tasks[item.Client]) == 0 {
+		fq.clients = append(fq.clients, item.Client)
+	}
+	fq.tasks[item.Client] = append(fq.tasks[item.Client], item)
+	fq.size++
+}
+
+// Take the oldest task of the client whose turn it is, who then goes to the back
+func (fq *fairQueue) pop() (QueueItem, bool) {
+	if len(fq.clients) == 0 {
+		return QueueItem{}, false
+	}
+
+	client := fq.clients[0]
+	fq.clients = fq.clients[1:]
+
+	tasks := fq.tasks[client]
+	item := tasks[0]
+	if len(tasks) > 1 {
+		fq.tasks[client] = tasks[1:]
+		fq.clients = append(fq.clients, client)
+	} else {
+		delete(fq.tasks, client)
+	}
+	fq.size--
+
+	return item, true
+}
+
+func (fq *fairQueue) remove(client, id string) bool {
+	tasks := fq.tasks[client]
+	for i, item := range tasks {
+		if item.ID != id {
+			continue
+		}
+
+		if len(tasks) > 1 {
+			fq.tasks[client] = append(tasks[:i:i], tasks[i+1:]...)
+		} else {
+			delete(fq.tasks, client)
+			for j, queued := range fq.clients {
+				if queued == client {
+					fq.clients = append(fq.clients[:j:j], fq.clients[j+1:]...)
+					break
+				}
+			}
+		}
+		fq.size--
+		return true
+	}
+	return false
+}
+
+func generateRandomID() string {
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+func generateRandomString(length int) string {
+	bytes := make([]byte, length)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
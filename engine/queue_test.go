@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Queue items without running them, to check the order next takes them in
+func queueItems(pq *ProcessQueue, priority Priority, client string, count int) {
+	for i := 0; i < count; i++ {
+		pq.queues[priority].push(QueueItem{
+			ID:       fmt.Sprintf("%s%d", client, i+1),
+			Priority: priority,
+			Client:   client,
+		})
+	}
+}
+
+func drainQueue(pq *ProcessQueue) []string {
+	order := []string{}
+	for {
+		item, ok := pq.next()
+		if !ok {
+			return order
+		}
+		order = append(order, item.ID)
+	}
+}
+
+func TestQueueRoundRobinAcrossClients(t *testing.T) {
+	pq := NewProcessQueue(1)
+	queueItems(pq, PriorityInteractive, "a", 3)
+	queueItems(pq, PriorityInteractive, "b", 1)
+	queueItems(pq, PriorityInteractive, "c", 2)
+
+	want := []string{"a1", "b1", "c1", "a2", "c2", "a3"}
+	if order := drainQueue(pq); !reflect.DeepEqual(order, want) {
+		t.Errorf("got %v, want %v", order, want)
+	}
+	if status := pq.GetStatus(); status.Queued != 0 || status.QueuedClients != 0 {
+		t.Errorf("drained queue reports %d tasks of %d clients", status.Queued, status.QueuedClients)
+	}
+}
+
+func TestQueueInteractiveBeforeBatch(t *testing.T) {
+	pq := NewProcessQueue(1)
+	queueItems(pq, PriorityBatch, "job", 2)
+	queueItems(pq, PriorityInteractive, "a", 2)
+	queueItems(pq, PriorityInteractive, "b", 1)
+
+	want := []string{"a1", "b1", "a2", "job1", "job2"}
+	if order := drainQueue(pq); !reflect.DeepEqual(order, want) {
+		t.Errorf("got %v, want %v", order, want)
+	}
+}
+
+func TestQueueBatchIsNotStarved(t *testing.T) {
+	pq := NewProcessQueue(1)
+	queueItems(pq, PriorityBatch, "job", 3)
+	queueItems(pq, PriorityInteractive, "a", 10)
+
+	want := []string{
+		"a1", "a2", "a3", "a4", "job1",
+		"a5", "a6", "a7", "a8", "job2",
+		"a9", "a10", "job3",
+	}
+	if order := drainQueue(pq); !reflect.DeepEqual(order, want) {
+		t.Errorf("got %v, want %v", order, want)
+	}
+
+	// Interactive work started while no batch was waiting doesn't count against the next job
+	queueItems(pq, PriorityInteractive, "b", 4)
+	drainQueue(pq)
+	queueItems(pq, PriorityBatch, "job", 1)
+	queueItems(pq, PriorityInteractive, "c", 1)
+	if order := drainQueue(pq); !reflect.DeepEqual(order, []string{"c1", "job1"}) {
+		t.Errorf("got %v, want [c1 job1]", order)
+	}
+}
@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"math"
+)
+
+// Windowed-sinc interpolation kernel: the number of zero crossings on each side
+// sets the filter length, the Kaiser beta its stopband attenuation (~80 dB)
+const (
+	sincZeroCrossings = 16
+	sincResolution    = 512
+	sincKaiserBeta    = 8.6
+	// Keep the passband slightly below Nyquist so the transition band doesn't alias
+	sincRolloff = 0.95
+)
+
+var sincTable = buildSincTable()
+
+// Convert interleaved samples between sample rates, channel counts and bit depths.
+// Zero fields of to keep the values of from.
+func convertAudioFormat(data []int, from *WAVHeader, to *WAVHeader) []int {
+	target := resolveTargetHeader(from, to)
+	if target.SampleRate == from.SampleRate &&
+		target.NumChannels == from.NumChannels &&
+		target.BitsPerSample == from.BitsPerSample {
+		return data
+	}
+
+	channels := samplesToFloat(data, int(from.NumChannels), int(from.BitsPerSample))
+	channels = remixChannels(channels, int(target.NumChannels))
+	for i, channel := range channels {
+		channels[i] = resampleSinc(channel, int(from.SampleRate), int(target.SampleRate))
+	}
+	return floatToSamples(channels, int(target.BitsPerSample))
+}
+
+// Fill the zero fields of a requested format from an actual one
+func resolveTargetHeader(from *WAVHeader, to *WAVHeader) *WAVHeader {
+	target := &WAVHeader{
+		SampleRate:    from.SampleRate,
+		NumChannels:   from.NumChannels,
+		BitsPerSample: from.BitsPerSample,
+	}
+	if to == nil {
+		return target
+	}
+	if to.SampleRate != 0 {
+		target.SampleRate = to.SampleRate
+	}
+	if to.NumChannels != 0 {
+		target.NumChannels = to.NumChannels
+	}
+	if to.BitsPerSample != 0 {
+		target.BitsPerSample = to.BitsPerSample
+	}
+	return target
+}
+
+// De-interleave samples into one slice per channel, scaled to [-1, 1)
+func samplesToFloat(data []int, numChannels, bitsPerSample int) [][]float64 {
+	frames := len(data) / numChannels
+	scale := float64(int64(1) << uint(bitsPerSample-1))
+
+	channels := make([][]float64, numChannels)
+	for ch := range channels {
+		channels[ch] = make([]float64, frames)
+	}
+
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < numChannels; ch++ {
+			sample := data[i*numChannels+ch]
+			if bitsPerSample == 8 {
+				// 8-bit WAV samples are unsigned
+				sample -= 128
+			}
+			channels[ch][i] = float64(sample) / scale
+		}
+	}
+
+	return channels
+}
+
+// Interleave channels back into integer samples, rounding and clipping to the bit depth
+func floatToSamples(channels [][]float64, bitsPerSample int) []int {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	frames := len(channels[0])
+	scale := float64(int64(1) << uint(bitsPerSample-1))
+	maxValue := scale - 1
+
+	out := make([]int, frames*len(channels))
+	for i := 0; i < frames; i++ {
+		for ch, channel := range channels {
+			value := math.Round(channel[i] * scale)
+			if value > maxValue {
+				value = maxValue
+			} else if value < -scale {
+				value = -scale
+			}
+
+			sample := int(value)
+			if bitsPerSample == 8 {
+				sample += 128
+			}
+			out[i*len(channels)+ch] = sample
+		}
+	}
+
+	return out
+}
+
+// Downmix to mono by averaging, or spread channels over a wider layout
+func remixChannels(channels [][]float64, numChannels int) [][]float64 {
+	if len(channels) == numChannels {
+		return channels
+	}
+
+	frames := len(channels[0])
+	out := make([][]float64, numChannels)
+
+	if numChannels == 1 {
+		mono := make([]float64, frames)
+		for _, channel := range channels {
+			for i, value := range channel {
+				mono[i] += value / float64(len(channels))
+			}
+		}
+		out[0] = mono
+		return out
+	}
+
+	for ch := range out {
+		out[ch] = append([]float64(nil), channels[ch%len(channels)]...)
+	}
+	return out
+}
+
+// Band-limited resampling of one channel with a Kaiser-windowed sinc filter.
+// When downsampling the filter cutoff follows the new Nyquist frequency.
+func resampleSinc(input []float64, fromRate, toRate int) []float64 {
+	if fromRate == toRate || len(input) == 0 {
+		return input
+	}
+
+	ratio := float64(toRate) / float64(fromRate)
+	cutoff := math.Min(1, ratio) * sincRolloff
+	// Half the filter length, in input samples
+	halfWidth := float64(sincZeroCrossings) / cutoff
+
+	outLength := int(int64(len(input)) * int64(toRate) / int64(fromRate))
+	output := make([]float64, outLength)
+
+	for i := range output {
+		position := float64(i) / ratio
+		first := int(math.Ceil(position - halfWidth))
+		last := int(math.Floor(position + halfWidth))
+		if first < 0 {
+			first = 0
+		}
+		if last >= len(input) {
+			last = len(input) - 1
+		}
+
+		var sum, weights float64
+		for j := first; j <= last; j++ {
+			weight := sincKernel(cutoff * (position - float64(j)))
+			sum += weight * input[j]
+			weights += weight
+		}
+
+		// Normalizing keeps the gain at 1 near the edges, where taps are missing
+		if weights != 0 {
+			output[i] = sum / weights
+		}
+	}
+
+	return output
+}
+
+// Look up the windowed sinc at x (in zero crossings), interpolating the table
+func sincKernel(x float64) float64 {
+	x = math.Abs(x)
+	if x >= sincZeroCrossings {
+		return 0
+	}
+
+	position := x * sincResolution
+	index := int(position)
+	fraction := position - float64(index)
+	return sincTable[index] + (sincTable[index+1]-sincTable[index])*fraction
+}
+
+func buildSincTable() []float64 {
+	table := make([]float64, sincZeroCrossings*sincResolution+2)
+	norm := besselI0(sincKaiserBeta)
+
+	for i := range table {
+		x := float64(i) / sincResolution
+		if x >= sincZeroCrossings {
+			continue
+		}
+
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+
+		ratio := x / sincZeroCrossings
+		window := besselI0(sincKaiserBeta*math.Sqrt(1-ratio*ratio)) / norm
+		table[i] = sinc * window
+	}
+
+	return table
+}
+
+// Zeroth-order modified Bessel function of the first kind, for the Kaiser window
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
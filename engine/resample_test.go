@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+// Sine samples at the given rate and amplitude (0-1 of full scale), as 16-bit integers
+func sineSamples(frequency float64, rate, frames int, amplitude float64) []int {
+	data := make([]int, frames)
+	for i := range data {
+		data[i] = int(math.Round(amplitude * 32767 * math.Sin(2*math.Pi*frequency*float64(i)/float64(rate))))
+	}
+	return data
+}
+
+// Amplitude of the frequency component of samples, ignoring the first and last 10 ms
+func toneAmplitude(samples []float64, frequency float64, rate int) float64 {
+	edge := rate / 100
+	var re, im float64
+	for i := edge; i < len(samples)-edge; i++ {
+		phase := 2 * math.Pi * frequency * float64(i) / float64(rate)
+		re += samples[i] * math.Cos(phase)
+		im += samples[i] * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(samples)-2*edge)
+}
+
+func TestResampleRateAndLength(t *testing.T) {
+	tests := []struct {
+		from, to int
+	}{
+		{22050, 48000},
+		{22050, 16000},
+		{16000, 22050},
+		{48000, 44100},
+	}
+
+	for _, tt := range tests {
+		from := &WAVHeader{SampleRate: uint32(tt.from), NumChannels: 1, BitsPerSample: 16}
+		to := &WAVHeader{SampleRate: uint32(tt.to)}
+		input := sineSamples(440, tt.from, tt.from, 0.5)
+
+		output := convertAudioFormat(input, from, to)
+		if len(output) != tt.to {
+			t.Errorf("%d -> %d Hz: one second became %d samples, want %d", tt.from, tt.to, len(output), tt.to)
+			continue
+		}
+
+		// The tone keeps its pitch and level at the new rate
+		channel := samplesToFloat(output, 1, 16)[0]
+		if amplitude := toneAmplitude(channel, 440, tt.to); math.Abs(amplitude-0.5) > 0.01 {
+			t.Errorf("%d -> %d Hz: 440 Hz tone at amplitude %.3f, want 0.5", tt.from, tt.to, amplitude)
+		}
+	}
+}
+
+func TestResampleFiltersAliases(t *testing.T) {
+	// 10 kHz is above the Nyquist frequency of 16 kHz audio and would fold back to 6 kHz
+	input := make([]float64, 22050)
+	for i := range input {
+		input[i] = 0.5 * math.Sin(2*math.Pi*10000*float64(i)/22050)
+	}
+
+	output := resampleSinc(input, 22050, 16000)
+	if alias := toneAmplitude(output, 6000, 16000); alias > 0.5*math.Pow(10, -60.0/20) {
+		t.Errorf("alias at 6 kHz has amplitude %.5f, want it 60 dB down", alias)
+	}
+}
+
+func TestConvertAudioFormatChannelsAndBits(t *testing.T) {
+	from := &WAVHeader{SampleRate: 22050, NumChannels: 2, BitsPerSample: 16}
+	// Left at half scale, right silent
+	input := []int{16384, 0, -16384, 0}
+
+	output := convertAudioFormat(input, from, &WAVHeader{NumChannels: 1, BitsPerSample: 8})
+	want := []int{128 + 32, 128 - 32}
+	if len(output) != len(want) || output[0] != want[0] || output[1] != want[1] {
+		t.Errorf("downmixed to 8-bit mono: got %v, want %v", output, want)
+	}
+
+	if output := convertAudioFormat(input, from, &WAVHeader{}); &output[0] != &input[0] {
+		t.Error("an empty target format converted the samples")
+	}
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Pause before the first retry of a sentence, doubled for every further one
+const retryBackoff = 500 * time.Millisecond
+
+// PiperError is a piper run that crashed, hung or wrote nothing. These are worth retrying,
+// unlike a missing model or executable, which would fail the same way again.
+type PiperError struct {
+	Err error
+	// Last few KB piper wrote to stderr
+	Stderr string
+}
+
+func (pe *PiperError) Error() string {
+	if line := lastLine(pe.Stderr); line != "" {
+		return fmt.Sprintf("piper failed: %v - %s", pe.Err, line)
+	}
+	return fmt.Sprintf("piper failed: %v", pe.Err)
+}
+
+func (pe *PiperError) Unwrap() error {
+	return pe.Err
+}
+
+// A piper run killed because its context ended. It reads as the context error, but keeps
+// what piper wrote to stderr so a timed-out attempt can report it.
+type killedRunError struct {
+	Err    error
+	Stderr string
+}
+
+func (ke *killedRunError) Error() string {
+	return ke.Err.Error()
+}
+
+func (ke *killedRunError) Unwrap() error {
+	return ke.Err
+}
+
+// SentenceError says which sentence of a conversion failed, after how many tries and why
+type SentenceError struct {
+	// Position of the sentence in the conversion, from 0
+	Index    int
+	Sentence string
+	Attempts int
+	Err      error
+}
+
+func (se *SentenceError) Error() string {
+	return fmt.Sprintf("sentence %d (\"%s\") failed after %d attempt(s): %v", se.Index+1, TruncateString(se.Sentence, 50), se.Attempts, se.Err)
+}
+
+func (se *SentenceError) Unwrap() error {
+	return se.Err
+}
+
+// What piper wrote to stderr on the last attempt, if it ran at all
+func (se *SentenceError) Stderr() string {
+	var piperErr *PiperError
+	if errors.As(se.Err, &piperErr) {
+		return piperErr.Stderr
+	}
+	return ""
+}
+
+// Synthesize one sentence through the queue. Every attempt may run for at most the
+// sentence timeout; runs that crash, hang or write nothing go back to the queue after
+// a growing pause, up to the configured number of retries.
+func (e *Engine) synthesizeSentence(ctx context.Context, text, modelPath string, settings AudioSettings) (string, int, error) {
+	backoff := retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		result, err := e.queue.Add(ctx, func(ctx context.Context) (interface{}, error) {
+			return e.generateAudioWithTimeout(ctx, text, modelPath, settings)
+		})
+		if err == nil {
+			return result.(string), attempt, nil
+		}
+
+		var piperErr *PiperError
+		if ctx.Err() != nil || !errors.As(err, &piperErr) || attempt > e.config.SentenceRetries {
+			return "", attempt, err
+		}
+
+		log.Printf("[RETRY] ⚠️  Attempt %d of \"%s...\" failed, retrying in %v: %v", attempt, TruncateString(text, 50), backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", attempt, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// Run generateAudio, killing it once it takes longer than the sentence timeout
+func (e *Engine) generateAudioWithTimeout(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, e.config.SentenceTimeout)
+	defer cancel()
+
+	outputFile, err := e.generateAudio(attemptCtx, text, modelPath, settings)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		timeoutErr := &PiperError{Err: fmt.Errorf("timed out after %v", e.config.SentenceTimeout)}
+		// Whatever piper wrote before it was killed, often why it hung
+		var killed *killedRunError
+		if errors.As(err, &killed) {
+			timeoutErr.Stderr = killed.Stderr
+		}
+		return "", timeoutErr
+	}
+	return outputFile, err
+}
+
+func lastLine(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return strings.TrimSpace(text[i+1:])
+	}
+	return text
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fails the first failures calls with err, then synthesizes like a FakeBackend.
+// onCall, when set, runs before every call.
+type flakyBackend struct {
+	FakeBackend
+	failures int
+	err      error
+	onCall   func()
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (fb *flakyBackend) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	fb.mu.Lock()
+	fb.calls++
+	call := fb.calls
+	fb.mu.Unlock()
+
+	if fb.onCall != nil {
+		fb.onCall()
+	}
+	if call <= fb.failures {
+		return "", fb.err
+	}
+	return fb.FakeBackend.Synthesize(ctx, text, modelPath, settings)
+}
+
+func (fb *flakyBackend) callCount() int {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.calls
+}
+
+func TestRetryOnPiperError(t *testing.T) {
+	backend := &flakyBackend{failures: 1, err: &PiperError{Err: errors.New("exit status 1"), Stderr: "[error] crashed"}}
+	e := newTestEngine(t, backend)
+
+	outputFile, attempts, err := e.synthesizeSentence(context.Background(), "Hello.", testModelPath(t, e, "en_US-test"), DefaultAudioSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(outputFile)
+
+	if attempts != 2 || backend.callCount() != 2 {
+		t.Errorf("took %d attempts and %d calls, want 2", attempts, backend.callCount())
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	backend := &flakyBackend{failures: 10, err: &PiperError{Err: errors.New("exit status 1")}}
+	e := newTestEngineWithConfig(t, Config{Backend: backend, SentenceRetries: 1})
+
+	_, attempts, err := e.synthesizeSentence(context.Background(), "Hello.", testModelPath(t, e, "en_US-test"), DefaultAudioSettings())
+	var piperErr *PiperError
+	if !errors.As(err, &piperErr) {
+		t.Fatalf("got %v, want a *PiperError", err)
+	}
+	if attempts != 2 || backend.callCount() != 2 {
+		t.Errorf("took %d attempts and %d calls with one retry, want 2", attempts, backend.callCount())
+	}
+}
+
+func TestNoRetryOnOtherErrors(t *testing.T) {
+	backend := &flakyBackend{failures: 10, err: errors.New("model not found")}
+	e := newTestEngine(t, backend)
+
+	_, attempts, err := e.synthesizeSentence(context.Background(), "Hello.", testModelPath(t, e, "en_US-test"), DefaultAudioSettings())
+	if err == nil || attempts != 1 || backend.callCount() != 1 {
+		t.Errorf("got %v after %d attempts and %d calls, want a failure after 1", err, attempts, backend.callCount())
+	}
+}
+
+func TestNoRetryAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The run fails as piper would when killed, but the caller is gone
+	backend := &flakyBackend{failures: 10, err: &PiperError{Err: errors.New("signal: killed")}, onCall: cancel}
+	e := newTestEngine(t, backend)
+
+	start := time.Now()
+	_, attempts, err := e.synthesizeSentence(ctx, "Hello.", testModelPath(t, e, "en_US-test"), DefaultAudioSettings())
+	if err == nil || attempts != 1 || backend.callCount() != 1 {
+		t.Errorf("got %v after %d attempts and %d calls, want a failure after 1", err, attempts, backend.callCount())
+	}
+	if elapsed := time.Since(start); elapsed >= retryBackoff {
+		t.Errorf("waited %v before giving up", elapsed)
+	}
+}
+
+func TestSentenceErrorNamesSentence(t *testing.T) {
+	backend := &flakyBackend{failures: 10, err: &PiperError{Err: errors.New("exit status 1"), Stderr: "loading\n[error] bad phoneme"}}
+	e := newTestEngineWithConfig(t, Config{Backend: backend, SentenceRetries: -1})
+
+	conv, err := e.Prepare(ConvertRequest{Text: "The broken sentence.", ModelPath: testModelPath(t, e, "en_US-test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = e.Render(context.Background(), conv, nil)
+	var sentenceErr *SentenceError
+	if !errors.As(err, &sentenceErr) {
+		t.Fatalf("got %v, want a *SentenceError", err)
+	}
+	if sentenceErr.Index != 0 || sentenceErr.Attempts != 1 || sentenceErr.Stderr() != backend.err.(*PiperError).Stderr {
+		t.Errorf("got %+v", sentenceErr)
+	}
+
+	want := `sentence 1 ("The broken sentence.") failed after 1 attempt(s): piper failed: exit status 1 - [error] bad phoneme`
+	if sentenceErr.Error() != want {
+		t.Errorf("got %q, want %q", sentenceErr.Error(), want)
+	}
+	if !strings.Contains(err.Error(), "The broken sentence.") {
+		t.Errorf("returned error %q doesn't name the sentence", err)
+	}
+}
+
+// Writes a line to stderr and then hangs until killed, like a stuck piper
+type hangingBackend struct{}
+
+func (hb *hangingBackend) Synthesize(ctx context.Context, text, modelPath string, settings AudioSettings) (string, error) {
+	stderr := &tailBuffer{max: 4096}
+	stderr.Write([]byte("[warning] phonemizer stuck on \"" + text + "\"\n"))
+	<-ctx.Done()
+	return "", &killedRunError{Err: ctx.Err(), Stderr: stderr.String()}
+}
+
+func TestTimeoutKeepsStderr(t *testing.T) {
+	e := newTestEngineWithConfig(t, Config{Backend: &hangingBackend{}, SentenceTimeout: 50 * time.Millisecond, SentenceRetries: -1})
+
+	conv, err := e.Prepare(ConvertRequest{Text: "The sentence that hangs.", ModelPath: testModelPath(t, e, "en_US-test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = e.Render(context.Background(), conv, nil)
+	var sentenceErr *SentenceError
+	if !errors.As(err, &sentenceErr) {
+		t.Fatalf("got %v, want a *SentenceError", err)
+	}
+	if !strings.Contains(sentenceErr.Stderr(), "phonemizer stuck") {
+		t.Errorf("stderr of the timed-out attempt is %q", sentenceErr.Stderr())
+	}
+	if !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("got %q, want a timeout", err)
+	}
+}
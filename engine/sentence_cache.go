@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// On-disk cache of synthesized sentences, keyed by everything that affects the audio.
+// Entries are evicted least-recently-used first once the size cap is reached.
+type SentenceCache struct {
+	dir      string
+	maxBytes int64
+	size     int64
+	entries  map[string]*list.Element
+	lru      *list.List
+	hits     int64
+	misses   int64
+	mu       sync.Mutex
+}
+
+type cacheEntry struct {
+	key  string
+	path string
+	size int64
+}
+
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Entries   int   `json:"entries"`
+	SizeBytes int64 `json:"sizeBytes"`
+	MaxBytes  int64 `json:"maxBytes"`
+}
+
+func NewSentenceCache(dir string, maxBytes int64) (*SentenceCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	sc := &SentenceCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+
+	// Load entries left by previous runs, oldest first so recent ones end up at the front
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %v", err)
+	}
+
+	type existing struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	found := []existing{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".wav") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, existing{
+			key:     strings.TrimSuffix(file.Name(), ".wav"),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].modTime.Before(found[j].modTime)
+	})
+
+	for _, entry := range found {
+		sc.add(entry.key, entry.size)
+	}
+	sc.evict()
+
+	log.Printf("[CACHE] ✅ Sentence cache at %s: %d entries, %.1f/%.1f MB", dir, len(sc.entries), float64(sc.size)/1e6, float64(maxBytes)/1e6)
+	return sc, nil
+}
+
+// Build the cache key of a sentence rendered by backend ("" for piper).
+// The model file's size and modification time are included so replacing a model invalidates its entries.
+func sentenceCacheKey(backend, text, modelPath string, settings AudioSettings) (string, error) {
+	info, err := os.Stat(modelPath)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if backend != "" {
+		fmt.Fprintf(hash, "%s\x00", backend)
+	}
+	fmt.Fprintf(hash, "%s\x00%s\x00%d\x00%d\x00", text, modelPath, info.ModTime().UnixNano(), info.Size())
+	fmt.Fprintf(hash, "%d\x00%.3f\x00%.3f\x00%.3f", settings.Speaker, settings.NoiseScale, settings.LengthScale, settings.NoiseW)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Get a private copy of a cached sentence, which the caller may modify or remove
+func (sc *SentenceCache) Get(key string) (string, bool) {
+	sc.mu.Lock()
+	element, ok := sc.entries[key]
+	if !ok {
+		sc.misses++
+		sc.mu.Unlock()
+		return "", false
+	}
+	sc.lru.MoveToFront(element)
+	cachedPath := sc.path(key)
+	sc.mu.Unlock()
+
+	outputFile := filepath.Join(os.TempDir(), fmt.Sprintf("tts_%s.wav", generateRandomString(8)))
+	if err := copyFile(cachedPath, outputFile); err != nil {
+		log.Printf("[CACHE] ⚠️  Dropping unreadable entry %s: %v", key, err)
+		sc.mu.Lock()
+		sc.misses++
+		if current, ok := sc.entries[key]; ok && current == element {
+			sc.removeElement(element)
+		}
+		sc.mu.Unlock()
+		return "", false
+	}
+
+	// Keep the file's age in sync with its LRU position for the next restart
+	now := time.Now()
+	os.Chtimes(cachedPath, now, now)
+
+	sc.mu.Lock()
+	sc.hits++
+	sc.mu.Unlock()
+
+	return outputFile, true
+}
+
+// Store a copy of a freshly synthesized sentence
+func (sc *SentenceCache) Put(key, audioFile string) {
+	info, err := os.Stat(audioFile)
+	if err != nil || info.Size() > sc.maxBytes {
+		return
+	}
+
+	// Write under a temporary name so a concurrent Get never sees a partial file
+	tempPath := sc.path(key) + "." + generateRandomString(4) + ".tmp"
+	if err := copyFile(audioFile, tempPath); err != nil {
+		log.Printf("[CACHE] ⚠️  Could not cache sentence: %v", err)
+		os.Remove(tempPath)
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if err := os.Rename(tempPath, sc.path(key)); err != nil {
+		os.Remove(tempPath)
+		return
+	}
+
+	if element, ok := sc.entries[key]; ok {
+		sc.size -= element.Value.(*cacheEntry).size
+		sc.lru.Remove(element)
+		delete(sc.entries, key)
+	}
+	sc.add(key, info.Size())
+	sc.evict()
+}
+
+func (sc *SentenceCache) Stats() CacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return CacheStats{
+		Hits:      sc.hits,
+		Misses:    sc.misses,
+		Entries:   len(sc.entries),
+		SizeBytes: sc.size,
+		MaxBytes:  sc.maxBytes,
+	}
+}
+
+// add must be called with sc.mu held
+func (sc *SentenceCache) add(key string, size int64) {
+	sc.entries[key] = sc.lru.PushFront(&cacheEntry{key: key, path: sc.path(key), size: size})
+	sc.size += size
+}
+
+// evict must be called with sc.mu held
+func (sc *SentenceCache) evict() {
+	for sc.size > sc.maxBytes && sc.lru.Len() > 0 {
+		sc.removeElement(sc.lru.Back())
+	}
+}
+
+// removeElement must be called with sc.mu held
+func (sc *SentenceCache) removeElement(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	os.Remove(entry.path)
+	sc.size -= entry.size
+	sc.lru.Remove(element)
+	delete(sc.entries, entry.key)
+}
+
+func (sc *SentenceCache) path(key string) string {
+	return filepath.Join(sc.dir, key+".wav")
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
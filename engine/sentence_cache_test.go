@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write a file of size bytes to cache, standing in for a rendered sentence
+func putTestAudio(t *testing.T, sc *SentenceCache, key string, size int) {
+	t.Helper()
+
+	audioFile := filepath.Join(t.TempDir(), key+".wav")
+	if err := os.WriteFile(audioFile, []byte(strings.Repeat(key[:1], size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sc.Put(key, audioFile)
+}
+
+// Get key and return its contents, or "" on a miss
+func getTestAudio(t *testing.T, sc *SentenceCache, key string) string {
+	t.Helper()
+
+	outputFile, ok := sc.Get(key)
+	if !ok {
+		return ""
+	}
+	defer os.Remove(outputFile)
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSentenceCacheHitAndMiss(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	sc, err := NewSentenceCache(t.TempDir(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getTestAudio(t, sc, "missing"); got != "" {
+		t.Errorf("got %q for a key never stored", got)
+	}
+
+	putTestAudio(t, sc, "aaa", 10)
+	if got := getTestAudio(t, sc, "aaa"); got != strings.Repeat("a", 10) {
+		t.Errorf("got %q, want the stored audio", got)
+	}
+
+	// Get hands out a copy, so removing it leaves the entry intact
+	if got := getTestAudio(t, sc, "aaa"); got == "" {
+		t.Error("entry gone after a previous Get")
+	}
+
+	stats := sc.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 || stats.SizeBytes != 10 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestSentenceCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	sc, err := NewSentenceCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putTestAudio(t, sc, "aaa", 100)
+	putTestAudio(t, sc, "bbb", 100)
+	// Using aaa makes bbb the least recently used
+	getTestAudio(t, sc, "aaa")
+	putTestAudio(t, sc, "ccc", 100)
+
+	for key, want := range map[string]bool{"aaa": true, "bbb": false, "ccc": true} {
+		_, err := os.Stat(filepath.Join(dir, key+".wav"))
+		if (err == nil) != want {
+			t.Errorf("file of %s exists: %v, want %v", key, err == nil, want)
+		}
+		if got := getTestAudio(t, sc, key) != ""; got != want {
+			t.Errorf("%s cached: %v, want %v", key, got, want)
+		}
+	}
+	if size := sc.Stats().SizeBytes; size != 200 {
+		t.Errorf("cache holds %d bytes, want 200", size)
+	}
+
+	// A file bigger than the whole cache is never stored
+	putTestAudio(t, sc, "ddd", 300)
+	if got := getTestAudio(t, sc, "ddd"); got != "" || sc.Stats().Entries != 2 {
+		t.Errorf("oversized entry was cached: %+v", sc.Stats())
+	}
+}
+
+func TestSentenceCacheReload(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	sc, err := NewSentenceCache(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putTestAudio(t, sc, "aaa", 100)
+	putTestAudio(t, sc, "bbb", 100)
+	putTestAudio(t, sc, "ccc", 100)
+
+	// Files are reloaded in the order they were last used
+	now := time.Now()
+	for i, key := range []string{"bbb", "ccc", "aaa"} {
+		modTime := now.Add(time.Duration(i-3) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, key+".wav"), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leftovers of an interrupted Put are ignored
+	if err := os.WriteFile(filepath.Join(dir, "eee.wav.1234.tmp"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Over the smaller limit the oldest file, bbb, goes
+	reloaded, err := NewSentenceCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := reloaded.Stats(); stats.Entries != 2 || stats.SizeBytes != 200 {
+		t.Errorf("got %+v after reload, want 2 entries of 100 bytes", stats)
+	}
+	if got := getTestAudio(t, reloaded, "aaa"); got != strings.Repeat("a", 100) {
+		t.Errorf("got %q for aaa after reload", got)
+	}
+	if got := getTestAudio(t, reloaded, "bbb"); got != "" {
+		t.Error("oldest entry survived the reload")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bbb.wav")); !os.IsNotExist(err) {
+		t.Errorf("evicted file still on disk: %v", err)
+	}
+}
+
+func TestSentenceCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	modelPath := filepath.Join(dir, "voice.onnx")
+	otherModelPath := filepath.Join(dir, "other.onnx")
+	for _, path := range []string{modelPath, otherModelPath} {
+		if err := os.WriteFile(path, []byte("model"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := DefaultAudioSettings()
+	key := func(backend, text, modelPath string, settings AudioSettings) string {
+		t.Helper()
+		k, err := sentenceCacheKey(backend, text, modelPath, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	base := key("", "Hello.", modelPath, settings)
+	if again := key("", "Hello.", modelPath, settings); again != base {
+		t.Errorf("same sentence gave keys %s and %s", base, again)
+	}
+
+	slower := settings
+	slower.LengthScale = 1.2
+	otherSpeaker := settings
+	otherSpeaker.Speaker = 3
+
+	changed := map[string]string{
+		"text":     key("", "Hello!", modelPath, settings),
+		"model":    key("", "Hello.", otherModelPath, settings),
+		"settings": key("", "Hello.", modelPath, slower),
+		"speaker":  key("", "Hello.", modelPath, otherSpeaker),
+		"backend":  key("*engine.FakeBackend", "Hello.", modelPath, settings),
+	}
+
+	// Replacing the model file invalidates its entries
+	modTime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(modelPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	changed["model file"] = key("", "Hello.", modelPath, settings)
+
+	for what, k := range changed {
+		if k == base {
+			t.Errorf("changing the %s kept the key", what)
+		}
+	}
+
+	if _, err := sentenceCacheKey("", "Hello.", filepath.Join(dir, "missing.onnx"), settings); err == nil {
+		t.Error("got a key for a missing model")
+	}
+}
@@ -0,0 +1,330 @@
+package engine
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A run of SSML text sharing the same voice and settings, followed by an optional silence
+type SSMLSegment struct {
+	Text     string
+	Model    *Model
+	Settings AudioSettings
+	Break    time.Duration
+}
+
+// Pauses used by <break strength="..."> and by the end of a paragraph
+var ssmlBreakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     250 * time.Millisecond,
+	"medium":   500 * time.Millisecond,
+	"strong":   750 * time.Millisecond,
+	"x-strong": 1000 * time.Millisecond,
+}
+
+// Speaking rate multipliers for the named <prosody rate="..."> values
+var ssmlRates = map[string]float64{
+	"x-slow":  0.5,
+	"slow":    0.75,
+	"medium":  1.0,
+	"default": 1.0,
+	"fast":    1.25,
+	"x-fast":  1.5,
+}
+
+// Most silence that breaks may add up to after one segment and in a whole document,
+// so thousands of <break> elements can't make the joiner allocate hours of audio
+const (
+	maxSSMLSegmentBreak = 30 * time.Second
+	maxSSMLTotalBreak   = 5 * time.Minute
+)
+
+type ssmlContext struct {
+	model    *Model
+	settings AudioSettings
+}
+
+// Check whether text looks like an SSML document
+func isSSML(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "<speak")
+}
+
+// Parse SSML into segments with their own voice, settings and trailing silences.
+// Supports <speak>, <break>, <prosody rate>, <say-as>, <sub alias>, <voice name>, <p> and <s>;
+// other elements are read as plain text.
+func (e *Engine) parseSSML(input string, model *Model, settings AudioSettings) ([]SSMLSegment, error) {
+	decoder := xml.NewDecoder(strings.NewReader(input))
+	decoder.Entity = xml.HTMLEntity
+
+	segments := []SSMLSegment{}
+	stack := []ssmlContext{{model: model, settings: settings}}
+	var text strings.Builder
+
+	// say-as and sub replace the text they enclose, so it is collected separately
+	var inner *strings.Builder
+	var innerElement xml.StartElement
+
+	current := func() *ssmlContext {
+		return &stack[len(stack)-1]
+	}
+
+	flush := func() {
+		content := strings.TrimSpace(text.String())
+		text.Reset()
+		if content == "" {
+			return
+		}
+		segments = append(segments, SSMLSegment{
+			Text:     content,
+			Model:    current().model,
+			Settings: current().settings,
+		})
+	}
+
+	var totalBreak time.Duration
+	addBreak := func(pause time.Duration) error {
+		flush()
+		if len(segments) == 0 {
+			// Nothing to pause after yet
+			return nil
+		}
+		segment := &segments[len(segments)-1]
+		segment.Break += pause
+		totalBreak += pause
+		if segment.Break > maxSSMLSegmentBreak {
+			return invalidRequest(fmt.Errorf("SSML breaks after \"%s\" add up to more than %v", TruncateString(segment.Text, 50), maxSSMLSegmentBreak))
+		}
+		if totalBreak > maxSSMLTotalBreak {
+			return invalidRequest(fmt.Errorf("SSML breaks add up to more than %v", maxSSMLTotalBreak))
+		}
+		return nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidRequest(fmt.Errorf("invalid SSML: %v", err))
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if inner != nil {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "break":
+				pause, err := parseSSMLBreak(t)
+				if err != nil {
+					return nil, invalidRequest(err)
+				}
+				if err := addBreak(pause); err != nil {
+					return nil, err
+				}
+
+			case "say-as", "sub":
+				inner = &strings.Builder{}
+				innerElement = t
+
+			case "prosody":
+				flush()
+				ctx := *current()
+				if rate := ssmlAttr(t, "rate"); rate != "" {
+					multiplier, err := parseSSMLRate(rate)
+					if err != nil {
+						return nil, invalidRequest(err)
+					}
+					ctx.settings.LengthScale = ctx.settings.LengthScale / multiplier
+				}
+				stack = append(stack, ctx)
+
+			case "voice":
+				flush()
+				ctx := *current()
+				if name := ssmlAttr(t, "name"); name != "" {
+					voiceModel, err := e.FindModelByID(name)
+					if err != nil {
+						return nil, invalidRequest(fmt.Errorf("SSML voice not found: %s", name))
+					}
+					ctx.model = voiceModel
+				}
+				if speaker := ssmlAttr(t, "speaker"); speaker != "" {
+					if speakerID, err := strconv.Atoi(speaker); err == nil {
+						ctx.settings.Speaker = speakerID
+					}
+				}
+				stack = append(stack, ctx)
+
+			case "p", "s":
+				flush()
+			}
+
+		case xml.EndElement:
+			if inner != nil {
+				if t.Name.Local != innerElement.Name.Local {
+					continue
+				}
+				text.WriteString(" " + interpretSSMLText(innerElement, inner.String()) + " ")
+				inner = nil
+				continue
+			}
+
+			switch t.Name.Local {
+			case "prosody", "voice":
+				flush()
+				if len(stack) > 1 {
+					stack = stack[:len(stack)-1]
+				}
+			case "p":
+				if err := addBreak(ssmlBreakStrengths["medium"]); err != nil {
+					return nil, err
+				}
+			case "s":
+				flush()
+			}
+
+		case xml.CharData:
+			if inner != nil {
+				inner.Write(t)
+			} else {
+				text.Write(t)
+			}
+		}
+	}
+
+	flush()
+
+	log.Printf("[SSML] Parsed %d segments", len(segments))
+	return segments, nil
+}
+
+// Resolve the replacement text of <say-as> and <sub>
+func interpretSSMLText(element xml.StartElement, content string) string {
+	content = strings.TrimSpace(content)
+
+	if element.Name.Local == "sub" {
+		if alias := ssmlAttr(element, "alias"); alias != "" {
+			return alias
+		}
+		return content
+	}
+
+	switch ssmlAttr(element, "interpret-as") {
+	case "characters", "spell-out", "verbatim":
+		// Read each letter or digit on its own
+		letters := []string{}
+		for _, r := range content {
+			if r != ' ' {
+				letters = append(letters, string(r))
+			}
+		}
+		return strings.Join(letters, " ")
+	case "digits", "telephone":
+		digits := []string{}
+		for _, r := range content {
+			if r >= '0' && r <= '9' {
+				digits = append(digits, string(r))
+			}
+		}
+		return strings.Join(digits, " ")
+	}
+
+	return content
+}
+
+func parseSSMLBreak(element xml.StartElement) (time.Duration, error) {
+	if value := ssmlAttr(element, "time"); value != "" {
+		pause, err := parseSSMLTime(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid break time: %s", value)
+		}
+		return pause, nil
+	}
+
+	if strength := ssmlAttr(element, "strength"); strength != "" {
+		if pause, ok := ssmlBreakStrengths[strength]; ok {
+			return pause, nil
+		}
+		return 0, fmt.Errorf("invalid break strength: %s", strength)
+	}
+
+	return ssmlBreakStrengths["medium"], nil
+}
+
+// Parse SSML times such as "500ms", "1.5s" or a bare number of milliseconds
+func parseSSMLTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	unit := time.Millisecond
+	switch {
+	case strings.HasSuffix(value, "ms"):
+		value = strings.TrimSuffix(value, "ms")
+	case strings.HasSuffix(value, "s"):
+		value = strings.TrimSuffix(value, "s")
+		unit = time.Second
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid time")
+	}
+
+	// Cap breaks so a typo can't produce minutes of silence
+	pause := time.Duration(number * float64(unit))
+	if pause > 10*time.Second {
+		pause = 10 * time.Second
+	}
+	return pause, nil
+}
+
+// Parse a prosody rate ("slow", "80%", "1.2") into a speed multiplier
+func parseSSMLRate(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	if multiplier, ok := ssmlRates[value]; ok {
+		return multiplier, nil
+	}
+
+	var multiplier float64
+	var err error
+	if strings.HasSuffix(value, "%") {
+		multiplier, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+			// Relative change, e.g. "+20%"
+			multiplier = 100 + multiplier
+		}
+		multiplier /= 100
+	} else {
+		multiplier, err = strconv.ParseFloat(value, 64)
+	}
+
+	if err != nil || multiplier <= 0 {
+		return 0, fmt.Errorf("invalid prosody rate: %s", value)
+	}
+
+	if multiplier < 0.25 {
+		multiplier = 0.25
+	}
+	if multiplier > 4 {
+		multiplier = 4
+	}
+	return multiplier, nil
+}
+
+func ssmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// What a test expects of one parsed segment
+type wantSegment struct {
+	text        string
+	voice       string
+	lengthScale float64
+	speaker     int
+	pause       time.Duration
+}
+
+func TestParseSSML(t *testing.T) {
+	e := newTestEngine(t, nil)
+	model, err := e.FindModelByID("en_US-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []wantSegment
+	}{
+		{
+			name:  "plain text",
+			input: `<speak>Hello there.</speak>`,
+			want:  []wantSegment{{text: "Hello there.", voice: "en_US-test", lengthScale: 1}},
+		},
+		{
+			name:  "break time and strength",
+			input: `<speak>One.<break time="300ms"/>Two.<break time="1.5s"/>Three.<break strength="strong"/><break/>Four.</speak>`,
+			want: []wantSegment{
+				{text: "One.", voice: "en_US-test", lengthScale: 1, pause: 300 * time.Millisecond},
+				{text: "Two.", voice: "en_US-test", lengthScale: 1, pause: 1500 * time.Millisecond},
+				{text: "Three.", voice: "en_US-test", lengthScale: 1, pause: 1250 * time.Millisecond},
+				{text: "Four.", voice: "en_US-test", lengthScale: 1},
+			},
+		},
+		{
+			name:  "break before any text is dropped",
+			input: `<speak><break time="2s"/>Hi.</speak>`,
+			want:  []wantSegment{{text: "Hi.", voice: "en_US-test", lengthScale: 1}},
+		},
+		{
+			name:  "long break is capped",
+			input: `<speak>Wait.<break time="90s"/>Done.</speak>`,
+			want: []wantSegment{
+				{text: "Wait.", voice: "en_US-test", lengthScale: 1, pause: 10 * time.Second},
+				{text: "Done.", voice: "en_US-test", lengthScale: 1},
+			},
+		},
+		{
+			name:  "prosody rate",
+			input: `<speak><prosody rate="slow">Slow.</prosody><prosody rate="200%">Fast.<prosody rate="+100%">Faster.</prosody></prosody>Normal.</speak>`,
+			want: []wantSegment{
+				{text: "Slow.", voice: "en_US-test", lengthScale: 1 / 0.75},
+				{text: "Fast.", voice: "en_US-test", lengthScale: 0.5},
+				{text: "Faster.", voice: "en_US-test", lengthScale: 0.25},
+				{text: "Normal.", voice: "en_US-test", lengthScale: 1},
+			},
+		},
+		{
+			name:  "say-as and sub",
+			input: `<speak>Call <say-as interpret-as="telephone">555-12</say-as>, spell <say-as interpret-as="characters">abc</say-as> and read <sub alias="World Wide Web">WWW</sub>.</speak>`,
+			want:  []wantSegment{{text: "Call  5 5 5 1 2 , spell  a b c  and read  World Wide Web .", voice: "en_US-test", lengthScale: 1}},
+		},
+		{
+			name:  "voice and speaker",
+			input: `<speak>Hello.<voice name="es_MX-test" speaker="2">Hola.</voice>Bye.</speak>`,
+			want: []wantSegment{
+				{text: "Hello.", voice: "en_US-test", lengthScale: 1},
+				{text: "Hola.", voice: "es_MX-test", lengthScale: 1, speaker: 2},
+				{text: "Bye.", voice: "en_US-test", lengthScale: 1},
+			},
+		},
+		{
+			name:  "paragraphs pause and sentences split",
+			input: `<speak><p><s>First one.</s><s>Second one.</s></p><p>Next paragraph.</p></speak>`,
+			want: []wantSegment{
+				{text: "First one.", voice: "en_US-test", lengthScale: 1},
+				{text: "Second one.", voice: "en_US-test", lengthScale: 1, pause: 500 * time.Millisecond},
+				{text: "Next paragraph.", voice: "en_US-test", lengthScale: 1, pause: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "entities and unknown elements",
+			input: `<speak>Tom &amp; Jerry <emphasis>really</emphasis> run.</speak>`,
+			want:  []wantSegment{{text: "Tom & Jerry really run.", voice: "en_US-test", lengthScale: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := e.parseSSML(tt.input, model, DefaultAudioSettings())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) != len(tt.want) {
+				t.Fatalf("got %d segments %+v, want %d", len(segments), segments, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := segments[i]
+				if got.Text != want.text || got.Model.ID != want.voice || got.Settings.Speaker != want.speaker || got.Break != want.pause {
+					t.Errorf("segment %d is %q by %s (speaker %d) with a %v break, want %q by %s (speaker %d) with %v",
+						i, got.Text, got.Model.ID, got.Settings.Speaker, got.Break, want.text, want.voice, want.speaker, want.pause)
+				}
+				if diff := got.Settings.LengthScale - want.lengthScale; diff > 1e-9 || diff < -1e-9 {
+					t.Errorf("segment %d has length scale %v, want %v", i, got.Settings.LengthScale, want.lengthScale)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSSMLErrors(t *testing.T) {
+	e := newTestEngine(t, nil)
+	model, err := e.FindModelByID("en_US-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unclosed element", `<speak><p>Hello.</speak>`, "invalid SSML"},
+		{"bad break time", `<speak>Hi.<break time="soon"/></speak>`, "invalid break time: soon"},
+		{"bad break strength", `<speak>Hi.<break strength="huge"/></speak>`, "invalid break strength: huge"},
+		{"bad rate", `<speak><prosody rate="fastest">Hi.</prosody></speak>`, "invalid prosody rate: fastest"},
+		{"unknown voice", `<speak><voice name="nobody">Hi.</voice></speak>`, "SSML voice not found: nobody"},
+		{"breaks after one segment", `<speak>Hi.` + strings.Repeat(`<break time="10s"/>`, 4) + `</speak>`, "add up to more than 30s"},
+		{"breaks in the document", `<speak>` + strings.Repeat(`Hi.<break time="10s"/>`, 31) + `</speak>`, "add up to more than 5m0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.parseSSML(tt.input, model, DefaultAudioSettings())
+			if !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("got %v, want an invalid request", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSSMLRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"x-slow", 0.5},
+		{"fast", 1.25},
+		{"80%", 0.8},
+		{"+20%", 1.2},
+		{"-50%", 0.5},
+		{"1.5", 1.5},
+		{"10%", 0.25},
+		{"9", 4},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSSMLRate(tt.value)
+		if err != nil || got-tt.want > 1e-9 || tt.want-got > 1e-9 {
+			t.Errorf("parseSSMLRate(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-1", "-100%", "quick"} {
+		if _, err := parseSSMLRate(value); err == nil {
+			t.Errorf("parseSSMLRate(%q) succeeded", value)
+		}
+	}
+}
@@ -0,0 +1,1408 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter code blocks from text
+func filterCodeBlocks(text string) string {
+	re := regexp.MustCompile("(?s)```[^`\\n]*\\n.*?```")
+	return re.ReplaceAllString(text, "")
+}
+
+// Process line breaks
+func processLineBreaks(text string) string {
+	log.Printf("[LINE_BREAKS] Original text: \"%s\"", TruncateString(text, 200))
+
+	processedText := text
+
+	// Handle paragraph breaks (double line breaks)
+	processedText = regexp.MustCompile(`\n\s*\n`).ReplaceAllString(processedText, ". ")
+
+	// Handle single line breaks more carefully
+	processedText = regexp.MustCompile(`([.!?¿¡…])\s*\n`).ReplaceAllString(processedText, "$1 ")
+	processedText = regexp.MustCompile(`([^.!?¿¡…])\s*\n\s*([A-ZÁÉÍÓÚÑÜ])`).ReplaceAllString(processedText, "$1. $2")
+	processedText = strings.ReplaceAll(processedText, "\n", " ")
+
+	// Clean up spacing
+	processedText = regexp.MustCompile(`\s+`).ReplaceAllString(processedText, " ")
+	processedText = strings.TrimSpace(processedText)
+
+	// Handle special cases for better speech flow
+	processedText = spaceColons(processedText)
+
+	// Clean up multiple periods
+	processedText = regexp.MustCompile(`\.{4,}`).ReplaceAllString(processedText, "...")
+	// Replace exactly 2 dots with 1 (but preserve 3+ dots which are ellipsis)
+	processedText = regexp.MustCompile(`\.{2}`).ReplaceAllStringFunc(processedText, func(match string) string {
+		// Check if it's part of ellipsis by looking at context
+		return "."
+	})
+	// Restore ellipsis if broken
+	processedText = regexp.MustCompile(`\.{3,}`).ReplaceAllString(processedText, "...")
+
+	log.Printf("[LINE_BREAKS] Final processed text: \"%s\"", TruncateString(processedText, 200))
+
+	return processedText
+}
+
+var colonPattern = regexp.MustCompile(`([a-zA-Z])\s*:\s*`)
+
+// Leave one space after a colon that follows a letter, except for the "://" of URLs
+func spaceColons(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, match := range colonPattern.FindAllStringSubmatchIndex(text, -1) {
+		colon := match[0] + strings.IndexByte(text[match[0]:match[1]], ':')
+		if strings.HasPrefix(text[colon+1:], "//") {
+			continue
+		}
+		sb.WriteString(text[last:match[0]])
+		sb.WriteString(text[match[2]:match[3]])
+		sb.WriteString(": ")
+		last = match[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// Apply the compiled replacements of a model, in order
+func applyReplacements(text string, replacements []ReplacementRule) string {
+	if text == "" || len(replacements) == 0 {
+		return text
+	}
+
+	log.Printf("[REPLACEMENTS] Starting text: '%s'", TruncateString(text, 100))
+	processedText := applyRules(text, replacements, "REPLACEMENTS")
+
+	if processedText != text {
+		log.Printf("[REPLACEMENTS] Final text: '%s'", TruncateString(processedText, 100))
+	} else {
+		log.Println("[REPLACEMENTS] No changes made to text")
+	}
+
+	return processedText
+}
+
+// ReplacementRule rewrites text before synthesis: a literal or regex find, and what to say instead
+type ReplacementRule struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+	// Find is a regular expression; Replace can then refer to groups as $1
+	Regex         bool `json:"regex,omitempty"`
+	CaseSensitive bool `json:"caseSensitive,omitempty"`
+	// Only match when not glued to other letters or digits
+	WholeWord bool `json:"wholeWord,omitempty"`
+
+	// Set for ["find", "replace"] pairs of model cards, which keep their original matching
+	legacy  bool
+	pattern *regexp.Regexp
+}
+
+// A rule from a ["find", "replace"] pair: case-insensitive, starting at a word boundary and
+// ending at one unless find ends with a period (abbreviations), as pairs have always matched
+func legacyReplacement(find, replace string) ReplacementRule {
+	expr := `(?i)\b` + regexp.QuoteMeta(find)
+	if !strings.HasSuffix(find, ".") {
+		expr += `\b`
+	}
+	return ReplacementRule{Find: find, Replace: replace, legacy: true, pattern: regexp.MustCompile(expr)}
+}
+
+// Compile the pattern of a rule; rules must be compiled before they are applied
+func (rule *ReplacementRule) Compile() error {
+	if rule.Find == "" {
+		return fmt.Errorf("Find text is required")
+	}
+
+	expr := rule.Find
+	if !rule.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !rule.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid regex %q: %v", rule.Find, err)
+	}
+	rule.pattern = pattern
+	return nil
+}
+
+// Apply the rule to text, returning the new text and the number of replacements made
+func (rule *ReplacementRule) apply(text string) (string, int) {
+	var sb strings.Builder
+	last, count := 0, 0
+
+	for _, loc := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start == end || (rule.WholeWord && !isStandalone(text, start, end)) {
+			continue
+		}
+
+		sb.WriteString(text[last:start])
+		if rule.Regex || rule.legacy {
+			sb.Write(rule.pattern.ExpandString(nil, rule.Replace, text, loc))
+		} else {
+			sb.WriteString(rule.Replace)
+		}
+		last = end
+		count++
+	}
+
+	if count == 0 {
+		return text, 0
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), count
+}
+
+// Apply compiled rules in order
+func applyRules(text string, rules []ReplacementRule, tag string) string {
+	for i := range rules {
+		var count int
+		text, count = rules[i].apply(text)
+		if count > 0 {
+			log.Printf("[%s] '%s' → '%s' (%d replacements)", tag, rules[i].Find, rules[i].Replace, count)
+		}
+	}
+	return text
+}
+
+// Normalize text for TTS
+func normalizeTextForTTS(text string) string {
+	log.Printf("[NORMALIZE] Starting normalization: \"%s\"", TruncateString(text, 100))
+
+	normalized := text
+
+	// Handle line breaks
+	normalized = regexp.MustCompile(`\n\s*\n`).ReplaceAllString(normalized, ". ")
+	normalized = strings.ReplaceAll(normalized, "\n", " ")
+
+	// Normalize quotes and dashes
+	normalized = strings.ReplaceAll(normalized, "\u201c", "\"") // "
+	normalized = strings.ReplaceAll(normalized, "\u201d", "\"") // "
+	normalized = strings.ReplaceAll(normalized, "\u2018", "\"") // '
+	normalized = strings.ReplaceAll(normalized, "\u2019", "\"") // '
+	normalized = strings.ReplaceAll(normalized, "\u2013", "-")  // –
+	normalized = strings.ReplaceAll(normalized, "\u2014", "-")  // —
+	normalized = strings.ReplaceAll(normalized, "\u2026", "...") // …
+
+	// Fix malformed punctuation combinations
+	normalized = strings.ReplaceAll(normalized, "¿¡", "¿")
+	normalized = strings.ReplaceAll(normalized, "¡¿", "¡")
+	normalized = strings.ReplaceAll(normalized, "?!", "?")
+	normalized = strings.ReplaceAll(normalized, "!?", "!")
+
+	// Remove duplicate punctuation marks
+	normalized = regexp.MustCompile(`¿¿+`).ReplaceAllString(normalized, "¿")
+	normalized = regexp.MustCompile(`¡¡+`).ReplaceAllString(normalized, "¡")
+	normalized = regexp.MustCompile(`\?\?+`).ReplaceAllString(normalized, "?")
+	normalized = regexp.MustCompile(`!!+`).ReplaceAllString(normalized, "!")
+
+	// Ensure proper question format
+	normalized = regexp.MustCompile(`¿([^?]*?)\?`).ReplaceAllStringFunc(normalized, func(match string) string {
+		content := strings.TrimPrefix(strings.TrimSuffix(match, "?"), "¿")
+		return "¿" + strings.TrimSpace(content) + "?"
+	})
+
+	// Ensure proper exclamation format
+	normalized = regexp.MustCompile(`¡([^!]*?)!`).ReplaceAllStringFunc(normalized, func(match string) string {
+		content := strings.TrimPrefix(strings.TrimSuffix(match, "!"), "¡")
+		return "¡" + strings.TrimSpace(content) + "!"
+	})
+
+	// Fix incomplete patterns - questions starting with ¿ but ending with .
+	normalized = regexp.MustCompile(`¿\s*([^?]*?)\.`).ReplaceAllString(normalized, "¿$1?")
+	// Fix incomplete patterns - exclamations starting with ¡ but ending with .
+	normalized = regexp.MustCompile(`¡\s*([^!]*?)\.`).ReplaceAllString(normalized, "¡$1!")
+
+	// Fix sentences ending with colon
+	normalized = regexp.MustCompile(`:\s*$`).ReplaceAllString(normalized, ".")
+	// Replace colon followed by uppercase letter with period and space
+	normalized = regexp.MustCompile(`:\s*([A-ZÁÉÍÓÚÑÜ])`).ReplaceAllString(normalized, ". $1")
+
+	// Clean up spacing
+	normalized = regexp.MustCompile(`\s+([.!?¿¡,;:])`).ReplaceAllString(normalized, "$1")
+	normalized = regexp.MustCompile(`([.!?])\s*([¿¡])`).ReplaceAllString(normalized, "$1 $2")
+
+	// Ensure proper spacing after punctuation
+	normalized = regexp.MustCompile(`([.!?])\s*([A-ZÁÉÍÓÚÑÜ])`).ReplaceAllString(normalized, "$1 $2")
+	normalized = regexp.MustCompile(`([,:;])\s*([A-ZÁÉÍÓÚÑÜ])`).ReplaceAllString(normalized, "$1 $2")
+
+	// Clean up multiple periods
+	normalized = regexp.MustCompile(`\.{4,}`).ReplaceAllString(normalized, "...")
+	// Replace exactly 2 dots with 1 (preserving ellipsis)
+	for strings.Contains(normalized, "..") && !strings.Contains(normalized, "...") {
+		normalized = strings.ReplaceAll(normalized, "..", ".")
+	}
+
+	// Remove duplicate punctuation
+	normalized = regexp.MustCompile(`([!?]){2,}`).ReplaceAllString(normalized, "$1")
+
+	// Normalize whitespace
+	normalized = regexp.MustCompile(`\s+`).ReplaceAllString(normalized, " ")
+	normalized = strings.TrimSpace(normalized)
+
+	log.Printf("[NORMALIZE] Final result: \"%s\"", TruncateString(normalized, 100))
+	return normalized
+}
+
+// Split text into sentences
+func splitSentences(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return []string{}
+	}
+
+	log.Printf("[SPLIT] Original text: \"%s\"", text)
+
+	// Normalize text first
+	normalizedText := normalizeTextForTTS(text)
+
+	sentences := splitAtSentenceEnds(normalizedText)
+
+	// Process and clean sentences
+	processedSentences := []string{}
+	for _, sentence := range sentences {
+		// Enhance sentence
+		sentence = enhanceSentenceForTTS(sentence)
+
+		if len(sentence) > 3 {
+			// Split long sentences
+			if len(sentence) > 400 {
+				chunks := splitLongSentence(sentence)
+				processedSentences = append(processedSentences, chunks...)
+			} else {
+				processedSentences = append(processedSentences, sentence)
+			}
+		}
+	}
+
+	// Merge short fragments
+	finalSentences := mergeShortFragments(processedSentences)
+
+	if len(finalSentences) > 0 {
+		log.Printf("[SPLIT] Text divided into %d segments:", len(finalSentences))
+		for i, sentence := range finalSentences {
+			log.Printf("[SPLIT] %d: \"%s\"", i+1, sentence)
+		}
+	}
+
+	return finalSentences
+}
+
+// Cut text at sentence ends, leaving the sentences as they are
+func splitAtSentenceEnds(text string) []string {
+	// Common abbreviations - ONLY real abbreviations that end with period
+	abbreviations := []string{
+		// Spanish titles
+		"Sr.", "Sra.", "Srta.", "Dr.", "Dra.", "Prof.", "Profa.", 
+		"Lic.", "Licda.", "Ing.", "Inga.", "Arq.", "Arqa.", 
+		"Mtro.", "Mtra.",
+		// Common abbreviations
+		"etc.", "vs.", "p.ej.",
+		// English abbreviations
+		"Mr.", "Mrs.", "Ms.", "Inc.", "Ltd.", "Corp.", "Co.",
+		"e.g.", "i.e.", "cf.", "vol.", "cap.", "art.", 
+		"núm.", "pág.", "ed.", "op.cit.",
+	}
+
+	// Protect abbreviations by replacing them with placeholders
+	protectedText := text
+	protectionMap := make(map[string]string)
+	
+	for i, abbrev := range abbreviations {
+		placeholder := fmt.Sprintf("__ABBREV_%d__", i)
+		// Simple string replacement - no regex needed
+		protectedText = strings.ReplaceAll(protectedText, abbrev, placeholder)
+		protectionMap[placeholder] = abbrev
+	}
+
+	// Split sentences more intelligently
+	// Look for: period/question/exclamation + space + (uppercase letter OR start of new paragraph)
+	// But NOT if it's a single letter followed by period (like "S. i" which should be "Si")
+	
+	sentences := []string{}
+	currentSentence := ""
+	runes := []rune(protectedText)
+	
+	for i := 0; i < len(runes); i++ {
+		currentSentence += string(runes[i])
+		
+		// Check if this is a sentence boundary
+		if (runes[i] == '.' || runes[i] == '!' || runes[i] == '?') {
+			// Look ahead to see what comes next
+			if i+1 < len(runes) {
+				// Skip whitespace to find next meaningful character
+				nextMeaningfulIdx := i + 1
+				for nextMeaningfulIdx < len(runes) && unicode.IsSpace(runes[nextMeaningfulIdx]) {
+					nextMeaningfulIdx++
+				}
+				
+				if nextMeaningfulIdx < len(runes) {
+					nextMeaningful := runes[nextMeaningfulIdx]
+					
+					// This is a sentence boundary if:
+					// 1. Next character is uppercase AND
+					// 2. Current sentence has at least 10 characters (avoid splitting "S. i" -> "S." + "i...")
+					// 3. OR next character is opening punctuation (¿¡)
+					if (unicode.IsUpper(nextMeaningful) && len(strings.TrimSpace(currentSentence)) > 10) ||
+						nextMeaningful == '¿' || nextMeaningful == '¡' {
+						
+						// This is a real sentence boundary
+						sentence := strings.TrimSpace(currentSentence)
+						if len(sentence) > 3 {
+							sentences = append(sentences, sentence)
+							log.Printf("[SPLIT] Extracted sentence: \"%s\"", TruncateString(sentence, 80))
+						}
+						currentSentence = ""
+					}
+				}
+			} else {
+				// End of text
+				sentence := strings.TrimSpace(currentSentence)
+				if len(sentence) > 3 {
+					sentences = append(sentences, sentence)
+					log.Printf("[SPLIT] Extracted final sentence: \"%s\"", TruncateString(sentence, 80))
+				}
+				currentSentence = ""
+			}
+		}
+	}
+	
+	// Add any remaining text
+	if len(strings.TrimSpace(currentSentence)) > 3 {
+		sentences = append(sentences, strings.TrimSpace(currentSentence))
+		log.Printf("[SPLIT] Extracted remaining text: \"%s\"", TruncateString(currentSentence, 80))
+	}
+
+	// Restore abbreviations
+	for i, sentence := range sentences {
+		for placeholder, original := range protectionMap {
+			sentence = strings.ReplaceAll(sentence, placeholder, original)
+		}
+		sentences[i] = sentence
+	}
+
+	return sentences
+}
+
+// Split text into sentences as written, for showing them: unlike splitSentences the text
+// isn't normalized and no punctuation is added. Short fragments are merged the same way.
+func splitSourceSentences(text string) []string {
+	return mergeShortFragments(splitAtSentenceEnds(strings.Join(strings.Fields(text), " ")))
+}
+
+// Enhance sentence for TTS
+func enhanceSentenceForTTS(sentence string) string {
+	enhanced := regexp.MustCompile(`[\r\n\t]+`).ReplaceAllString(sentence, " ")
+	enhanced = regexp.MustCompile(`\s+`).ReplaceAllString(enhanced, " ")
+	enhanced = strings.TrimSpace(enhanced)
+
+	if enhanced == "" {
+		return enhanced
+	}
+
+	// Check if sentence has ending punctuation
+	hasEndingPunctuation := regexp.MustCompile(`[.!?…]$`).MatchString(enhanced)
+
+	if !hasEndingPunctuation {
+		// Add appropriate ending
+		if strings.HasPrefix(enhanced, "¿") || regexp.MustCompile(`(?i)\b(qué|quién|cuándo|dónde|cómo|por qué|cuál)\b`).MatchString(enhanced) {
+			enhanced += "?"
+		} else if strings.HasPrefix(enhanced, "¡") || regexp.MustCompile(`(?i)\b(wow|increíble|excelente|fantástico)\b`).MatchString(enhanced) {
+			enhanced += "!"
+		} else {
+			enhanced += "."
+		}
+	}
+
+	// Add opening punctuation if missing
+	if strings.HasSuffix(enhanced, "?") && !strings.Contains(enhanced, "¿") && !regexp.MustCompile(`(?i)\b(yes|no|si|sí)\b`).MatchString(enhanced) {
+		enhanced = "¿" + enhanced
+	}
+	if strings.HasSuffix(enhanced, "!") && !strings.Contains(enhanced, "¡") && regexp.MustCompile(`(?i)\b(wow|increíble|excelente|fantástico|bravo|genial)\b`).MatchString(enhanced) {
+		enhanced = "¡" + enhanced
+	}
+
+	// Remove duplicate punctuation
+	enhanced = regexp.MustCompile(`¿¿+`).ReplaceAllString(enhanced, "¿")
+	enhanced = regexp.MustCompile(`¡¡+`).ReplaceAllString(enhanced, "¡")
+	enhanced = regexp.MustCompile(`\?\?+`).ReplaceAllString(enhanced, "?")
+	enhanced = regexp.MustCompile(`!!+`).ReplaceAllString(enhanced, "!")
+
+	return enhanced
+}
+
+// Split long sentences
+func splitLongSentence(sentence string) []string {
+	chunks := []string{}
+	naturalBreaksPattern := regexp.MustCompile(`(?i)([,:;]\s+(?:pero|sin embargo|además|por tanto|por lo tanto|no obstante|mientras|cuando|donde|como|que|si|aunque|porque|ya que|dado que|puesto que))`)
+	
+	parts := naturalBreaksPattern.Split(sentence, -1)
+	currentChunk := ""
+
+	for _, part := range parts {
+		if len(currentChunk) > 0 && len(currentChunk+part) > 200 {
+			if strings.TrimSpace(currentChunk) != "" {
+				chunks = append(chunks, enhanceSentenceForTTS(strings.TrimSpace(currentChunk)))
+			}
+			currentChunk = part
+		} else {
+			currentChunk += part
+		}
+	}
+
+	if strings.TrimSpace(currentChunk) != "" {
+		chunks = append(chunks, enhanceSentenceForTTS(strings.TrimSpace(currentChunk)))
+	}
+
+	if len(chunks) == 0 {
+		return []string{sentence}
+	}
+
+	return chunks
+}
+
+// Merge short fragments
+func mergeShortFragments(sentences []string) []string {
+	merged := []string{}
+
+	for i := 0; i < len(sentences); i++ {
+		sentence := sentences[i]
+		wordCount := len(regexp.MustCompile(`\b\w+\b`).FindAllString(sentence, -1))
+
+		if wordCount < 4 && len(sentence) < 30 {
+			if len(merged) > 0 {
+				// Merge with previous
+				merged[len(merged)-1] += " " + sentence
+			} else if i+1 < len(sentences) {
+				// Merge with next
+				merged = append(merged, sentence+" "+sentences[i+1])
+				i++ // Skip next
+			} else {
+				merged = append(merged, sentence)
+			}
+		} else {
+			merged = append(merged, sentence)
+		}
+	}
+
+	return merged
+}
+
+// Filter text segment with comprehensive processing.
+// lexiconRules are the shared lexicon entries of the language, applied after the model's replacements.
+func filterTextSegment(textSegment string, language string, modelReplacements []ReplacementRule, lexiconRules []ReplacementRule) string {
+	log.Printf("[FILTER] Processing segment: '%s'", TruncateString(textSegment, 100))
+
+	// Remove code blocks
+	text := filterCodeBlocks(textSegment)
+	log.Printf("[FILTER] After code block removal: '%s'", TruncateString(text, 100))
+
+	// Process line breaks
+	text = processLineBreaks(text)
+	log.Printf("[FILTER] After line break processing: '%s'", TruncateString(text, 100))
+
+	// Apply replacements
+	if len(modelReplacements) > 0 {
+		log.Printf("[FILTER] Using %d model-specific replacements from .onnx.json", len(modelReplacements))
+		text = applyReplacements(text, modelReplacements)
+	} else {
+		log.Println("[FILTER] No model replacements found in .onnx.json - no replacements applied")
+	}
+
+	// Shared lexicons of the language come after the model's own replacements
+	if len(lexiconRules) > 0 {
+		log.Printf("[FILTER] Using %d lexicon entries for %s", len(lexiconRules), language)
+		text = applyRules(text, lexiconRules, "LEXICON")
+	}
+
+	// Spell out numbers, dates, currencies... in the model's language.
+	// Replacements run first so their rules can still match digits.
+	text = verbalizeText(text, language)
+
+	// Final cleanup
+	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+	text = strings.TrimSpace(text)
+
+	log.Printf("[FILTER] Final processed text: '%s'", TruncateString(text, 100))
+	return text
+}
+
+// Shorten a string to maxLen bytes for logging
+func TruncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// Count words in text
+func countWords(text string) int {
+	return len(regexp.MustCompile(`\b\w+\b`).FindAllString(text, -1))
+}
+
+// Check if character is uppercase
+func isUpperCase(r rune) bool {
+	return unicode.IsUpper(r)
+}
+
+// Two-letter code of a model language such as "es_MX", "en-US", "Spanish" or "Español"
+func languageCode(language string) string {
+	code := strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(code, "_-"); i >= 0 {
+		code = code[:i]
+	}
+
+	switch code {
+	case "spanish", "español", "espanol", "castellano":
+		return "es"
+	case "english", "inglés", "ingles":
+		return "en"
+	}
+	return code
+}
+
+// Region of a model language such as "es_MX" or "en-GB", upper-cased; empty when not given
+func languageRegion(language string) string {
+	if i := strings.IndexAny(language, "_-"); i >= 0 {
+		return strings.ToUpper(strings.TrimSpace(language[i+1:]))
+	}
+	return ""
+}
+
+var (
+	// A number with optional thousands separators and decimals, in either convention
+	numberToken = `\d+(?:[.,]\d+)*`
+
+	dateNumberPattern     = regexp.MustCompile(`\b(\d{1,4})([/.-])(\d{1,2})([/.-])(\d{1,4})\b`)
+	timePattern           = regexp.MustCompile(`\b(\d{1,2}):(\d{2})(?::(\d{2}))?(?:\s?([aApP])\.?\s?[mM]\b(\.?))?`)
+	negativePattern       = regexp.MustCompile(`(^|[\s(])[-−](\d)`)
+	currencyPrefixPattern = regexp.MustCompile(`([€$£])\s?(` + numberToken + `)(?:\s(thousand|million|billion|trillion|mil|millón|millones)\b)?`)
+	currencySuffixPattern = regexp.MustCompile(`(` + numberToken + `)\s?(€|\$|£|EUR|USD|GBP)`)
+	percentPattern        = regexp.MustCompile(`(` + numberToken + `)\s?%`)
+	spanishOrdinalPattern = regexp.MustCompile(`(\d+)(?:\.?([ºª])|(era|ero|er|ra|ro|da|do|ta|to|va|vo|na|no|ma|mo))(\s?[CF]\b)?`)
+	englishOrdinalPattern = regexp.MustCompile(`(?i)(\d+)(st|nd|rd|th)`)
+	romanNumeralPattern   = regexp.MustCompile(`(\p{L}+)(\s+)([IVXLCDM]+)\b`)
+	numberPattern         = regexp.MustCompile(numberToken)
+	followingWordPattern  = regexp.MustCompile(`^\s+(\p{L}+)`)
+)
+
+// Words that introduce a Roman numeral read as a cardinal ("siglo XXI", "chapter IV")
+var romanNumeralKeywords = map[string]map[string]bool{
+	"es": {"siglo": true, "siglos": true, "capítulo": true, "capitulo": true, "tomo": true, "volumen": true,
+		"parte": true, "acto": true, "libro": true, "fase": true, "nivel": true, "título": true},
+	"en": {"century": true, "chapter": true, "part": true, "volume": true, "vol": true, "book": true,
+		"act": true, "phase": true, "level": true, "war": true, "title": true, "section": true},
+}
+
+var monthNames = map[string][]string{
+	"es": {"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	"en": {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+}
+
+// How a currency is read: the unit and its hundredth, singular and plural
+type currencyNames struct {
+	One, Many         string
+	CentOne, CentMany string
+	// Spanish nouns that need feminine numbers ("doscientas libras")
+	Feminine     bool
+	CentFeminine bool
+}
+
+var currencyCodes = map[string]string{"€": "EUR", "$": "USD", "£": "GBP", "EUR": "EUR", "USD": "USD", "GBP": "GBP"}
+
+var currencyWords = map[string]map[string]currencyNames{
+	"es": {
+		"EUR": {One: "euro", Many: "euros", CentOne: "céntimo", CentMany: "céntimos"},
+		"USD": {One: "dólar", Many: "dólares", CentOne: "centavo", CentMany: "centavos"},
+		"GBP": {One: "libra", Many: "libras", CentOne: "penique", CentMany: "peniques", Feminine: true},
+	},
+	"en": {
+		"EUR": {One: "euro", Many: "euros", CentOne: "cent", CentMany: "cents"},
+		"USD": {One: "dollar", Many: "dollars", CentOne: "cent", CentMany: "cents"},
+		"GBP": {One: "pound", Many: "pounds", CentOne: "penny", CentMany: "pence"},
+	},
+}
+
+// Spell out numbers, dates, times, currencies, percentages, ordinals and Roman numerals
+// in the model's language, so they aren't read digit by digit or with the wrong conventions.
+// Only Spanish and English have rules; other languages are returned unchanged.
+func verbalizeText(text, language string) string {
+	lang := languageCode(language)
+	if lang != "es" && lang != "en" {
+		return text
+	}
+
+	processedText := text
+	processedText = replaceStandalone(processedText, dateNumberPattern, func(groups []string) (string, bool) {
+		return verbalizeDate(groups, language)
+	})
+	processedText = replaceStandalone(processedText, timePattern, func(groups []string) (string, bool) {
+		return verbalizeTime(groups, lang)
+	})
+	processedText = negativePattern.ReplaceAllString(processedText, "${1}"+map[string]string{"es": "menos", "en": "minus"}[lang]+" $2")
+	processedText = replaceStandalone(processedText, currencyPrefixPattern, func(groups []string) (string, bool) {
+		return verbalizeCurrency(groups[2], groups[3], currencyCodes[groups[1]], lang)
+	})
+	processedText = replaceStandalone(processedText, currencySuffixPattern, func(groups []string) (string, bool) {
+		return verbalizeCurrency(groups[1], "", currencyCodes[groups[2]], lang)
+	})
+	processedText = replaceStandalone(processedText, percentPattern, func(groups []string) (string, bool) {
+		number, ok := spellNumber(groups[1], lang, spanishNeutral)
+		return number + map[string]string{"es": " por ciento", "en": " percent"}[lang], ok
+	})
+
+	if lang == "es" {
+		processedText = replaceStandalone(processedText, spanishOrdinalPattern, verbalizeSpanishOrdinal)
+	} else {
+		processedText = replaceStandalone(processedText, englishOrdinalPattern, func(groups []string) (string, bool) {
+			n, ok := parseSmallInt(groups[1])
+			return englishOrdinal(n), ok
+		})
+	}
+
+	processedText = replaceStandalone(processedText, romanNumeralPattern, func(groups []string) (string, bool) {
+		return verbalizeRomanNumeral(groups, lang)
+	})
+
+	processedText = replaceStandaloneIndex(processedText, numberPattern, func(groups []string, end int) (string, bool) {
+		form := spanishNeutral
+		if lang == "es" {
+			form = spanishFormBefore(processedText[end:])
+		}
+		return spellNumber(groups[0], lang, form)
+	})
+
+	if processedText != text {
+		log.Printf("[VERBALIZE] (%s) '%s'", lang, TruncateString(processedText, 100))
+	}
+	return processedText
+}
+
+// Replace matches of pattern that aren't glued to letters or digits ("MP3", "4K" are left alone).
+// replace gets the submatches and can decline a match by returning false.
+func replaceStandalone(text string, pattern *regexp.Regexp, replace func(groups []string) (string, bool)) string {
+	return replaceStandaloneIndex(text, pattern, func(groups []string, end int) (string, bool) {
+		return replace(groups)
+	})
+}
+
+// Like replaceStandalone, also passing where the match ends so the text after it can be checked
+func replaceStandaloneIndex(text string, pattern *regexp.Regexp, replace func(groups []string, end int) (string, bool)) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if !isStandalone(text, start, end) {
+			continue
+		}
+
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+
+		replacement, ok := replace(groups, end)
+		if !ok {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(replacement)
+		last = end
+	}
+
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// Check that text[start:end] isn't preceded or followed by a letter or digit
+func isStandalone(text string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Read a date such as "3/10/2024", "03-10-24" or "2024-10-03". Day and month order follow
+// the language: month first for US (or unspecified) English, day first otherwise.
+func verbalizeDate(groups []string, language string) (string, bool) {
+	if groups[2] != groups[4] {
+		return "", false
+	}
+
+	lang := languageCode(language)
+	first, _ := parseSmallInt(groups[1])
+	second, _ := parseSmallInt(groups[3])
+	third, _ := parseSmallInt(groups[5])
+
+	var day, month, year int64
+	switch {
+	case len(groups[1]) == 4 && len(groups[5]) <= 2:
+		year, month, day = first, second, third
+	case len(groups[1]) <= 2 && (len(groups[5]) == 2 || len(groups[5]) == 4):
+		day, month, year = first, second, third
+		region := languageRegion(language)
+		if lang == "en" && (region == "" || region == "US") {
+			day, month = second, first
+		}
+		// Only one reading makes sense when the first number can't be a month
+		if month > 12 && day <= 12 {
+			day, month = month, day
+		}
+		if len(groups[5]) == 2 {
+			year += 1900
+			if year < 1950 {
+				year += 100
+			}
+		}
+	default:
+		return "", false
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return "", false
+	}
+	monthName := monthNames[lang][month-1]
+
+	if lang == "es" {
+		return fmt.Sprintf("%s de %s de %s", spanishCardinal(day, spanishNeutral), monthName, spanishCardinal(year, spanishNeutral)), true
+	}
+	if region := languageRegion(language); region == "" || region == "US" {
+		return fmt.Sprintf("%s %s, %s", monthName, englishOrdinal(day), englishYear(year)), true
+	}
+	return fmt.Sprintf("the %s of %s, %s", englishOrdinal(day), monthName, englishYear(year)), true
+}
+
+// Read a clock time such as "10:30", "7:05 p.m." or "18:45:10"
+func verbalizeTime(groups []string, lang string) (string, bool) {
+	hours, _ := parseSmallInt(groups[1])
+	minutes, _ := parseSmallInt(groups[2])
+	if hours > 24 || minutes > 59 {
+		return "", false
+	}
+
+	var spoken string
+	switch {
+	case groups[3] != "":
+		seconds, _ := parseSmallInt(groups[3])
+		if seconds > 59 {
+			return "", false
+		}
+		if lang == "es" {
+			spoken = fmt.Sprintf("%s horas, %s minutos y %s segundos",
+				spanishCardinal(hours, spanishFeminine), spanishCardinal(minutes, spanishMasculine), spanishCardinal(seconds, spanishMasculine))
+		} else {
+			spoken = fmt.Sprintf("%s hours, %s minutes and %s seconds", englishCardinal(hours), englishCardinal(minutes), englishCardinal(seconds))
+		}
+	case lang == "es":
+		spoken = spanishCardinal(hours, spanishFeminine)
+		if minutes == 0 {
+			spoken += " en punto"
+		} else {
+			spoken += " y " + spanishCardinal(minutes, spanishNeutral)
+		}
+	default:
+		spoken = englishCardinal(hours)
+		switch {
+		case minutes == 0 && groups[4] == "":
+			spoken += " o'clock"
+		case minutes == 0:
+		case minutes < 10:
+			spoken += " oh " + englishCardinal(minutes)
+		default:
+			spoken += " " + englishCardinal(minutes)
+		}
+	}
+
+	if groups[4] != "" {
+		spoken += " " + strings.ToUpper(groups[4]) + " M" + groups[5]
+	}
+	return spoken, true
+}
+
+// Read an amount of money, e.g. "1.250,50" euros or "5" million dollars
+func verbalizeCurrency(amount, scale, code, lang string) (string, bool) {
+	names, ok := currencyWords[lang][code]
+	if !ok {
+		return "", false
+	}
+
+	whole, fraction, ok := parseNumberToken(amount, lang)
+	if !ok {
+		return "", false
+	}
+
+	form := spanishMasculine
+	if names.Feminine {
+		form = spanishFeminine
+	}
+
+	if scale != "" {
+		number, ok := spellNumber(amount, lang, form)
+		if !ok {
+			return "", false
+		}
+		if lang == "es" && scale != "mil" {
+			return number + " " + scale + " de " + names.Many, true
+		}
+		return number + " " + scale + " " + names.Many, true
+	}
+
+	if len(fraction) > 2 {
+		// Not a sum in units and hundredths, read it as a plain number
+		number, ok := spellNumber(amount, lang, form)
+		return number + " " + names.Many, ok
+	}
+	if len(fraction) == 1 {
+		fraction += "0"
+	}
+	cents, _ := parseSmallInt(fraction)
+
+	parts := []string{}
+	if whole > 0 || cents == 0 {
+		unit := names.Many
+		if whole == 1 {
+			unit = names.One
+		}
+		if lang == "es" {
+			number := spanishCardinal(whole, form)
+			if strings.HasSuffix(number, "millón") || strings.HasSuffix(number, "millones") {
+				unit = "de " + unit
+			}
+			parts = append(parts, number+" "+unit)
+		} else {
+			parts = append(parts, englishCardinal(whole)+" "+unit)
+		}
+	}
+
+	if cents > 0 {
+		unit := names.CentMany
+		if cents == 1 {
+			unit = names.CentOne
+		}
+		if lang == "es" {
+			centForm := spanishMasculine
+			if names.CentFeminine {
+				centForm = spanishFeminine
+			}
+			parts = append(parts, spanishCardinal(cents, centForm)+" "+unit)
+		} else {
+			parts = append(parts, englishCardinal(cents)+" "+unit)
+		}
+	}
+
+	return strings.Join(parts, map[string]string{"es": " con ", "en": " and "}[lang]), true
+}
+
+// "2º", "1ª", "1er", "3ra" ... "25º C" is a temperature and is left alone
+func verbalizeSpanishOrdinal(groups []string) (string, bool) {
+	if groups[4] != "" {
+		return "", false
+	}
+	n, ok := parseSmallInt(groups[1])
+	if !ok || n < 1 || n > 100 {
+		return "", false
+	}
+
+	suffix := groups[2] + groups[3]
+	feminine := suffix == "ª" || strings.HasSuffix(suffix, "a")
+	return spanishOrdinal(n, feminine, suffix == "er"), true
+}
+
+// Read a Roman numeral after a keyword ("siglo XXI" -> "siglo veintiuno") or a capitalized
+// name ("Felipe II" -> "Felipe segundo", "Henry VIII" -> "Henry the eighth")
+func verbalizeRomanNumeral(groups []string, lang string) (string, bool) {
+	word, space, numeral := groups[1], groups[2], groups[3]
+	value, ok := parseRoman(numeral)
+	if !ok {
+		return "", false
+	}
+
+	if romanNumeralKeywords[lang][strings.ToLower(word)] {
+		if lang == "es" {
+			return word + space + spanishCardinal(int64(value), spanishNeutral), true
+		}
+		return word + space + englishCardinal(int64(value)), true
+	}
+
+	// Regnal numbers: a capitalized name followed by I, V and X only. Single letters are too
+	// ambiguous in English ("I", "Malcolm X") and X stays a letter in Spanish ("rayos X").
+	runes := []rune(word)
+	if len(runes) < 2 || !unicode.IsUpper(runes[0]) || strings.Trim(numeral, "IVX") != "" || value > 39 {
+		return "", false
+	}
+	if lang == "en" && len(numeral) == 1 || lang == "es" && numeral == "X" {
+		return "", false
+	}
+
+	if lang == "es" {
+		if value <= 10 {
+			return word + space + spanishOrdinal(int64(value), false, false), true
+		}
+		return word + space + spanishCardinal(int64(value), spanishNeutral), true
+	}
+	return word + space + "the " + englishOrdinal(int64(value)), true
+}
+
+// Value of a Roman numeral from I to MMMCMXCIX; only the canonical spelling is accepted
+func parseRoman(numeral string) (int, bool) {
+	values := map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+	total := 0
+	for i := 0; i < len(numeral); i++ {
+		value := values[numeral[i]]
+		if i+1 < len(numeral) && values[numeral[i+1]] > value {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+
+	if total <= 0 || total >= 4000 || formatRoman(total) != numeral {
+		return 0, false
+	}
+	return total, true
+}
+
+func formatRoman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var sb strings.Builder
+	for i, value := range values {
+		for n >= value {
+			sb.WriteString(symbols[i])
+			n -= value
+		}
+	}
+	return sb.String()
+}
+
+// Numbers above this are read digit by digit
+const maxSpelledNumber = 999999999999999
+
+// Split a number token into its whole part and decimal digits. "1.250,50" and "1,250.50"
+// are both understood; a single separator followed by three digits is a thousands
+// separator when it is the language's ("1.250" in Spanish, "1,250" in English).
+func parseNumberToken(token, lang string) (int64, string, bool) {
+	separators := strings.Count(token, ".") + strings.Count(token, ",")
+	if separators == 0 {
+		whole, ok := parseSmallInt(token)
+		return whole, "", ok
+	}
+
+	group := map[string]string{"es": ".", "en": ","}[lang]
+
+	lastIndex := strings.LastIndexAny(token, ".,")
+	last := token[lastIndex : lastIndex+1]
+
+	wholePart, fraction := token, ""
+	switch {
+	case strings.Contains(token, ".") && strings.Contains(token, ","):
+		// Mixed separators: the last one is the decimal point
+		group = map[string]string{".": ",", ",": "."}[last]
+		wholePart, fraction = token[:lastIndex], token[lastIndex+1:]
+	case separators > 1:
+		group = last
+	case last == group && len(token)-lastIndex-1 == 3:
+	default:
+		wholePart, fraction = token[:lastIndex], token[lastIndex+1:]
+	}
+
+	if strings.ContainsAny(fraction, ".,") {
+		return 0, "", false
+	}
+
+	groups := strings.Split(wholePart, group)
+	for i, digits := range groups {
+		if (i > 0 && len(digits) != 3) || (i == 0 && (len(digits) == 0 || len(digits) > 3 && len(groups) > 1)) {
+			return 0, "", false
+		}
+		if strings.ContainsAny(digits, ".,") {
+			return 0, "", false
+		}
+	}
+
+	whole, ok := parseSmallInt(strings.Join(groups, ""))
+	return whole, fraction, ok
+}
+
+func parseSmallInt(digits string) (int64, bool) {
+	if digits == "" || len(digits) > len(fmt.Sprint(maxSpelledNumber)) {
+		return 0, false
+	}
+	var n int64
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(r-'0')
+	}
+	return n, n <= maxSpelledNumber
+}
+
+// Spell a number token, with its decimals if any. Leading zeros ("007") and numbers too long
+// to spell are read digit by digit. In English, plain numbers from 1100 to 2099 are read as years.
+func spellNumber(token, lang string, form spanishForm) (string, bool) {
+	if len(token) > 1 && token[0] == '0' && !strings.ContainsAny(token, ".,") {
+		return spellDigits(token, lang), true
+	}
+	if !strings.ContainsAny(token, ".,") && len(token) > len(fmt.Sprint(maxSpelledNumber)) {
+		return spellDigits(token, lang), true
+	}
+
+	whole, fraction, ok := parseNumberToken(token, lang)
+	if !ok {
+		return "", false
+	}
+
+	if lang == "en" {
+		// Plain four-digit numbers are most likely years
+		if len(token) == 4 && whole >= 1100 && whole < 2100 {
+			return englishYear(whole), true
+		}
+		spoken := englishCardinal(whole)
+		if fraction != "" {
+			spoken += " point " + spellDigits(fraction, lang)
+		}
+		return spoken, true
+	}
+
+	if fraction == "" {
+		return spanishCardinal(whole, form), true
+	}
+	spoken := spanishCardinal(whole, spanishNeutral) + " coma "
+	if fraction[0] == '0' || len(fraction) > 3 {
+		return spoken + spellDigits(fraction, lang), true
+	}
+	decimals, _ := parseSmallInt(fraction)
+	return spoken + spanishCardinal(decimals, spanishNeutral), true
+}
+
+func spellDigits(digits, lang string) string {
+	words := []string{}
+	for _, r := range digits {
+		if lang == "es" {
+			words = append(words, spanishUnits[r-'0'])
+		} else {
+			words = append(words, englishOnes[r-'0'])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// How a Spanish "uno" is spelled: counting, before a masculine noun ("un", "veintiún")
+// or before a feminine one ("una", "doscientas")
+type spanishForm int
+
+const (
+	spanishNeutral spanishForm = iota
+	spanishMasculine
+	spanishFeminine
+)
+
+// Words after a number that aren't the noun it counts ("21 de enero", "3 o 4")
+var spanishNonNouns = map[string]bool{
+	"a": true, "al": true, "como": true, "con": true, "de": true, "del": true, "desde": true, "e": true,
+	"en": true, "entre": true, "era": true, "es": true, "fue": true, "hasta": true, "más": true, "menos": true,
+	"ni": true, "o": true, "para": true, "por": true, "que": true, "se": true, "sin": true, "sobre": true,
+	"son": true, "u": true, "y": true,
+}
+
+// Common nouns whose ending gives the wrong gender
+var spanishNounGender = map[string]spanishForm{
+	"día": spanishMasculine, "días": spanishMasculine, "mapa": spanishMasculine, "mapas": spanishMasculine,
+	"problema": spanishMasculine, "problemas": spanishMasculine, "sistema": spanishMasculine, "sistemas": spanishMasculine,
+	"tema": spanishMasculine, "temas": spanishMasculine, "programa": spanishMasculine, "programas": spanishMasculine,
+	"idioma": spanishMasculine, "idiomas": spanishMasculine, "planeta": spanishMasculine, "planetas": spanishMasculine,
+	"poema": spanishMasculine, "poemas": spanishMasculine, "clima": spanishMasculine, "drama": spanishMasculine,
+	"vez": spanishFeminine, "veces": spanishFeminine, "mujer": spanishFeminine, "mujeres": spanishFeminine,
+	"noche": spanishFeminine, "noches": spanishFeminine, "tarde": spanishFeminine, "tardes": spanishFeminine,
+	"calle": spanishFeminine, "calles": spanishFeminine, "gente": spanishFeminine, "parte": spanishFeminine,
+	"partes": spanishFeminine, "clase": spanishFeminine, "clases": spanishFeminine, "mano": spanishFeminine,
+	"manos": spanishFeminine, "foto": spanishFeminine, "fotos": spanishFeminine, "imagen": spanishFeminine,
+	"imágenes": spanishFeminine, "flor": spanishFeminine, "flores": spanishFeminine, "ley": spanishFeminine,
+	"leyes": spanishFeminine, "red": spanishFeminine, "redes": spanishFeminine, "llave": spanishFeminine,
+	"llaves": spanishFeminine, "nube": spanishFeminine, "nubes": spanishFeminine,
+}
+
+// Endings of feminine nouns and adjectives; other words are taken as masculine
+var spanishFeminineEndings = []string{"ción", "ciones", "sión", "siones", "dad", "dades", "tad", "tades", "tud", "tudes", "umbre", "umbres", "a", "as"}
+
+// Form of a Spanish number from the word after it: before a noun "uno" is shortened or made
+// feminine and hundreds agree ("veintiún libros", "veintiuna personas", "doscientas casas").
+// The gender is guessed from the ending, with a list of common exceptions; "mil" takes the
+// gender of the noun after it ("doscientas mil personas").
+func spanishFormBefore(rest string) spanishForm {
+	match := followingWordPattern.FindStringSubmatch(rest)
+	if match == nil {
+		return spanishNeutral
+	}
+
+	word := strings.ToLower(match[1])
+	if word == "mil" {
+		if form := spanishFormBefore(rest[len(match[0]):]); form == spanishFeminine {
+			return form
+		}
+		return spanishMasculine
+	}
+	if spanishNonNouns[word] {
+		return spanishNeutral
+	}
+	if form, ok := spanishNounGender[word]; ok {
+		return form
+	}
+	for _, ending := range spanishFeminineEndings {
+		if strings.HasSuffix(word, ending) {
+			return spanishFeminine
+		}
+	}
+	return spanishMasculine
+}
+
+var spanishUnits = []string{"cero", "uno", "dos", "tres", "cuatro", "cinco", "seis", "siete", "ocho", "nueve",
+	"diez", "once", "doce", "trece", "catorce", "quince", "dieciséis", "diecisiete", "dieciocho", "diecinueve",
+	"veinte", "veintiuno", "veintidós", "veintitrés", "veinticuatro", "veinticinco", "veintiséis", "veintisiete", "veintiocho", "veintinueve"}
+
+var spanishTens = []string{"", "", "", "treinta", "cuarenta", "cincuenta", "sesenta", "setenta", "ochenta", "noventa"}
+
+var spanishHundreds = []string{"", "ciento", "doscientos", "trescientos", "cuatrocientos", "quinientos", "seiscientos", "setecientos", "ochocientos", "novecientos"}
+
+func spanishCardinal(n int64, form spanishForm) string {
+	if n < 0 {
+		return "menos " + spanishCardinal(-n, form)
+	}
+	if n == 0 {
+		return "cero"
+	}
+
+	parts := []string{}
+	for _, scale := range []struct {
+		value     int64
+		one, many string
+	}{{1000000000000, "un billón", "billones"}, {1000000, "un millón", "millones"}} {
+		if n < scale.value {
+			continue
+		}
+		count := n / scale.value
+		if count == 1 {
+			parts = append(parts, scale.one)
+		} else {
+			parts = append(parts, spanishBelowMillion(count, spanishMasculine)+" "+scale.many)
+		}
+		n %= scale.value
+	}
+
+	if n > 0 {
+		parts = append(parts, spanishBelowMillion(n, form))
+	}
+	return strings.Join(parts, " ")
+}
+
+func spanishBelowMillion(n int64, form spanishForm) string {
+	thousands, rest := n/1000, n%1000
+
+	parts := []string{}
+	switch {
+	case thousands == 1:
+		parts = append(parts, "mil")
+	case thousands > 1:
+		thousandsForm := spanishMasculine
+		if form == spanishFeminine {
+			thousandsForm = spanishFeminine
+		}
+		parts = append(parts, spanishBelowThousand(thousands, thousandsForm)+" mil")
+	}
+
+	if rest > 0 {
+		parts = append(parts, spanishBelowThousand(rest, form))
+	}
+	return strings.Join(parts, " ")
+}
+
+func spanishBelowThousand(n int64, form spanishForm) string {
+	if n == 100 {
+		return "cien"
+	}
+
+	parts := []string{}
+	if hundreds := n / 100; hundreds > 0 {
+		word := spanishHundreds[hundreds]
+		if form == spanishFeminine && hundreds > 1 {
+			word = strings.TrimSuffix(word, "os") + "as"
+		}
+		parts = append(parts, word)
+	}
+
+	rest := n % 100
+	switch {
+	case rest == 0:
+	case rest < 30:
+		parts = append(parts, spanishUno(spanishUnits[rest], form))
+	default:
+		word := spanishTens[rest/10]
+		if rest%10 > 0 {
+			word += " y " + spanishUno(spanishUnits[rest%10], form)
+		}
+		parts = append(parts, word)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Shorten or make feminine a final "uno"
+func spanishUno(word string, form spanishForm) string {
+	if !strings.HasSuffix(word, "uno") {
+		return word
+	}
+	stem := strings.TrimSuffix(word, "uno")
+	switch form {
+	case spanishMasculine:
+		if stem == "" {
+			return "un"
+		}
+		return stem + "ún"
+	case spanishFeminine:
+		return stem + "una"
+	}
+	return word
+}
+
+var spanishOrdinalUnits = []string{"", "primero", "segundo", "tercero", "cuarto", "quinto", "sexto", "séptimo", "octavo", "noveno"}
+
+var spanishOrdinalTens = []string{"", "décimo", "vigésimo", "trigésimo", "cuadragésimo", "quincuagésimo", "sexagésimo", "septuagésimo", "octogésimo", "nonagésimo"}
+
+// Spanish ordinal from 1 to 100; shortened is "primer"/"tercer" before a masculine noun
+func spanishOrdinal(n int64, feminine, shortened bool) string {
+	var words []string
+	switch {
+	case n < 1 || n > 100:
+		return spanishCardinal(n, spanishNeutral)
+	case n == 100:
+		words = []string{"centésimo"}
+	case n == 11:
+		words = []string{"undécimo"}
+	case n == 12:
+		words = []string{"duodécimo"}
+	case n > 10 && n < 20:
+		words = []string{strings.Replace("decimo"+spanishOrdinalUnits[n%10], "decimooctavo", "decimoctavo", 1)}
+	default:
+		if n/10 > 0 {
+			words = append(words, spanishOrdinalTens[n/10])
+		}
+		if n%10 > 0 {
+			words = append(words, spanishOrdinalUnits[n%10])
+		}
+	}
+
+	last := len(words) - 1
+	switch {
+	case feminine:
+		for i, word := range words {
+			words[i] = strings.TrimSuffix(word, "o") + "a"
+		}
+	case shortened && (words[last] == "primero" || words[last] == "tercero"):
+		words[last] = strings.TrimSuffix(words[last], "o")
+	}
+	return strings.Join(words, " ")
+}
+
+var englishOnes = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+
+var englishTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+func englishCardinal(n int64) string {
+	if n < 0 {
+		return "minus " + englishCardinal(-n)
+	}
+	if n == 0 {
+		return "zero"
+	}
+
+	parts := []string{}
+	for _, scale := range []struct {
+		value int64
+		name  string
+	}{{1000000000000, "trillion"}, {1000000000, "billion"}, {1000000, "million"}, {1000, "thousand"}} {
+		if n >= scale.value {
+			parts = append(parts, englishBelowThousand(n/scale.value)+" "+scale.name)
+			n %= scale.value
+		}
+	}
+
+	if n > 0 {
+		parts = append(parts, englishBelowThousand(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+func englishBelowThousand(n int64) string {
+	parts := []string{}
+	if n >= 100 {
+		parts = append(parts, englishOnes[n/100]+" hundred")
+		n %= 100
+	}
+
+	switch {
+	case n == 0:
+	case n < 20:
+		parts = append(parts, englishOnes[n])
+	case n%10 == 0:
+		parts = append(parts, englishTens[n/10])
+	default:
+		parts = append(parts, englishTens[n/10]+"-"+englishOnes[n%10])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Years are read in pairs: "nineteen ninety-nine", "twenty twenty-four", "eighteen oh five"
+func englishYear(year int64) string {
+	switch {
+	case year < 1000 || year >= 10000 || (year >= 2000 && year < 2010):
+		return englishCardinal(year)
+	case year%100 == 0:
+		return englishCardinal(year/100) + " hundred"
+	case year%100 < 10:
+		return englishCardinal(year/100) + " oh " + englishCardinal(year%100)
+	}
+	return englishCardinal(year/100) + " " + englishCardinal(year%100)
+}
+
+var englishIrregularOrdinals = map[string]string{
+	"one": "first", "two": "second", "three": "third", "five": "fifth", "eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+}
+
+func englishOrdinal(n int64) string {
+	cardinal := englishCardinal(n)
+	split := strings.LastIndexAny(cardinal, " -") + 1
+	last := cardinal[split:]
+
+	switch {
+	case englishIrregularOrdinals[last] != "":
+		last = englishIrregularOrdinals[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return cardinal[:split] + last
+}
@@ -0,0 +1,92 @@
+package engine
+
+import "testing"
+
+func TestProcessLineBreaksColons(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a:b:c", "a: b: c"},
+		{"Note:read this", "Note: read this"},
+		{"Time : now", "Time: now"},
+		{"See https://example.com/a:b now", "See https://example.com/a: b now"},
+		{"ftp://host and http://host", "ftp://host and http://host"},
+		{"10:30", "10:30"},
+	}
+
+	for _, tt := range tests {
+		if got := processLineBreaks(tt.in); got != tt.want {
+			t.Errorf("processLineBreaks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProcessLineBreaks(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"First paragraph\n\nSecond", "First paragraph. Second"},
+		{"Ends here.\nNext line", "Ends here. Next line"},
+		{"A title\nStarts here", "A title. Starts here"},
+		{"keeps\ngoing", "keeps going"},
+	}
+
+	for _, tt := range tests {
+		if got := processLineBreaks(tt.in); got != tt.want {
+			t.Errorf("processLineBreaks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVerbalizeText(t *testing.T) {
+	tests := []struct {
+		language string
+		in       string
+		want     string
+	}{
+		// Cardinals agree with the noun after them
+		{"es_MX", "Tengo 21.", "Tengo veintiuno."},
+		{"es_MX", "Vinieron 21 personas.", "Vinieron veintiuna personas."},
+		{"es_MX", "Compré 21 libros.", "Compré veintiún libros."},
+		{"es_MX", "Hay 1 casa y 1 perro.", "Hay una casa y un perro."},
+		{"es_MX", "Son 200 personas y 200 euros.", "Son doscientas personas y doscientos euros."},
+		{"es_MX", "Había 200000 personas y 21000 libros.", "Había doscientas mil personas y veintiún mil libros."},
+		{"es_MX", "Hace 21 días.", "Hace veintiún días."},
+		{"es_MX", "El 21 de enero.", "El veintiuno de enero."},
+		{"es_MX", "Entre 3 y 4.", "Entre tres y cuatro."},
+		{"es_MX", "Vive en el 1.250 de la calle.", "Vive en el mil doscientos cincuenta de la calle."},
+		{"es_MX", "Ganó 1.000.000 de votos.", "Ganó un millón de votos."},
+		{"en_US", "I have 21 apples.", "I have twenty-one apples."},
+		{"en_US", "We had 1,250 users in 1999.", "We had one thousand two hundred fifty users in nineteen ninety-nine."},
+
+		// Ordinals
+		{"es_MX", "El 1er lugar y la 2ª vez.", "El primer lugar y la segunda vez."},
+		{"es_MX", "Quedó 3º.", "Quedó tercero."},
+		{"en_US", "The 2nd and 23rd.", "The second and twenty-third."},
+
+		// Decimals and percentages
+		{"es_MX", "Mide 3,5 metros.", "Mide tres coma cinco metros."},
+		{"es_MX", "Es el 25%.", "Es el veinticinco por ciento."},
+		{"en_US", "Pi is 3.14.", "Pi is three point one four."},
+		{"en_US", "Up 15%.", "Up fifteen percent."},
+
+		// Currency
+		{"es_MX", "Cuesta 3,50 €.", "Cuesta tres euros con cincuenta céntimos."},
+		{"es_MX", "Cuesta £21.", "Cuesta veintiuna libras."},
+		{"es_MX", "Cuesta $1.", "Cuesta un dólar."},
+		{"en_US", "It costs $3.50.", "It costs three dollars and fifty cents."},
+		{"en_US", "It costs €1.01.", "It costs one euro and one cent."},
+
+		// Glued to letters, or a language without rules
+		{"en_US", "An MP3 file.", "An MP3 file."},
+		{"fr_FR", "J'ai 21 ans.", "J'ai 21 ans."},
+	}
+
+	for _, tt := range tests {
+		if got := verbalizeText(tt.in, tt.language); got != tt.want {
+			t.Errorf("verbalizeText(%q, %s) = %q, want %q", tt.in, tt.language, got, tt.want)
+		}
+	}
+}
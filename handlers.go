@@ -8,18 +8,28 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+
+	"github.com/go-audio/audio"
+	"github.com/gorilla/mux"
+
+	"gopiper/engine"
 )
 
-// maxTextLength is defined in main.go as a global variable
-// It limits the maximum text length for TTS conversion (0 = no limit)
+// convertRequest is the body of /convert and /jobs: an engine request plus captions
+type convertRequest struct {
+	engine.ConvertRequest
+	// When set, /convert also returns SRT and WebVTT captions
+	Subtitles *SubtitleOptions `json:"subtitles"`
+}
 
 // GET /models - Get available models
 func getModelsHandler(w http.ResponseWriter, r *http.Request) {
+	models := ttsEngine.Models()
 	jsonResponse(w, map[string]interface{}{
 		"success": true,
-		"models":  availableModels,
-		"count":   len(availableModels),
+		"models":  models,
+		"count":   len(models),
 	}, http.StatusOK)
 }
 
@@ -39,8 +49,7 @@ func setModelPathsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	modelPaths = requestData.Paths
-	if err := scanModels(); err != nil {
+	if err := ttsEngine.SetModelPaths(requestData.Paths); err != nil {
 		errorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -48,7 +57,7 @@ func setModelPathsHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{
 		"success":    true,
 		"message":    "Model paths updated",
-		"modelCount": len(availableModels),
+		"modelCount": len(ttsEngine.Models()),
 	}, http.StatusOK)
 }
 
@@ -56,134 +65,71 @@ func setModelPathsHandler(w http.ResponseWriter, r *http.Request) {
 func convertHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DEBUG] 🚀 /convert route called")
 
-	var requestData struct {
-		Text      string                 `json:"text"`
-		ModelPath string                 `json:"modelPath"`
-		Settings  map[string]interface{} `json:"settings"`
-	}
-
+	var requestData convertRequest
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		errorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[DEBUG] 📥 Received request - text length: %d, modelPath: %s", len(requestData.Text), requestData.ModelPath)
-
-	if requestData.Text == "" {
-		errorResponse(w, "Text is required", http.StatusBadRequest)
-		return
-	}
-
-	// Check MAX_TEXT limit if set
-	if maxTextLength > 0 && len(requestData.Text) > maxTextLength {
-		errorResponse(w, fmt.Sprintf("Text exceeds maximum length of %d characters", maxTextLength), http.StatusBadRequest)
-		return
-	}
-
-	if requestData.ModelPath == "" {
-		errorResponse(w, "Model path is required", http.StatusBadRequest)
-		return
-	}
-
-	// Find model by path
-	model, err := findModelByPath(requestData.ModelPath)
+	conv, err := ttsEngine.Prepare(requestData.ConvertRequest)
 	if err != nil {
-		errorResponse(w, "Model not found", http.StatusNotFound)
-		return
-	}
-
-	log.Printf("[CONVERT] 🎤 Converting text with model: %s (%s)", model.Name, model.Language)
-	log.Printf("[CONVERT] 📝 Input text length: %d characters", len(requestData.Text))
-	log.Printf("[CONVERT] 🔧 About to start text filtering...")
-
-	// Apply comprehensive text filtering and replacements
-	processedText := filterTextSegment(requestData.Text, model.Replacements)
-
-	log.Printf("[CONVERT] 🔧 Text filtering completed")
-
-	if processedText == "" {
-		log.Printf("[CONVERT] ❌ Text became empty after processing")
-		errorResponse(w, "Text became empty after processing", http.StatusBadRequest)
+		errorResponse(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	log.Printf("[CONVERT] ✅ Text ready for synthesis: '%s'", truncateString(processedText, 100))
-
-	// Split into sentences
-	sentences := splitSentences(processedText)
-	log.Printf("[CONVERT] 📄 Split into %d sentences", len(sentences))
-
-	if len(sentences) == 0 {
-		errorResponse(w, "No valid sentences found in text", http.StatusBadRequest)
-		return
-	}
-
-	// Parse audio settings
-	settings := parseAudioSettings(requestData.Settings)
-
-	// Generate audio for all sentences in parallel
-	validSentences := []string{}
-	for _, s := range sentences {
-		if s != "" {
-			validSentences = append(validSentences, s)
-		}
-	}
-
-	audioFiles, err := generateAudioParallel(validSentences, requestData.ModelPath, settings)
+	result, err := ttsEngine.Render(requestContext(r), conv, nil)
 	if err != nil {
 		log.Printf("[CONVERT] ❌ Error generating audio: %v", err)
-		errorResponse(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if len(audioFiles) == 0 {
-		errorResponse(w, "Failed to generate any audio", http.StatusInternalServerError)
+		renderErrorResponse(w, err)
 		return
 	}
+	defer os.Remove(result.AudioPath)
 
-	var finalAudioPath string
-
-	if len(audioFiles) == 1 {
-		finalAudioPath = audioFiles[0]
-		log.Printf("[CONVERT] 🎵 Using single audio file")
-	} else {
-		// Concatenate multiple audio files
-		log.Printf("[CONVERT] 🔗 Concatenating %d audio files", len(audioFiles))
-		concatenatedPath := filepath.Join(os.TempDir(), fmt.Sprintf("final_%s.wav", generateRandomString(8)))
-		if err := concatenateAudio(audioFiles, concatenatedPath); err != nil {
-			log.Printf("[CONVERT] ❌ Error concatenating audio: %v", err)
-			errorResponse(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		finalAudioPath = concatenatedPath
-	}
-
-	// Read the WAV file and encode as base64 (no conversion needed, browsers support WAV)
+	// Read the audio file and encode as base64
 	log.Printf("[CONVERT] 🎵 Reading audio file...")
-	audioBuffer, err := os.ReadFile(finalAudioPath)
+	audioBuffer, err := os.ReadFile(result.AudioPath)
 	if err != nil {
 		log.Printf("[CONVERT] ❌ Error reading audio file: %v", err)
 		errorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer os.Remove(finalAudioPath)
 
 	audioBase64 := base64.StdEncoding.EncodeToString(audioBuffer)
 	audioSizeKB := len(audioBuffer) / 1024
 
-	log.Printf("[CONVERT] ✅ Conversion completed! Audio size: %dKB (WAV format)", audioSizeKB)
+	log.Printf("[CONVERT] ✅ Conversion completed! Audio size: %dKB (%s format)", audioSizeKB, conv.Format)
 
-	jsonResponse(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"success":       true,
-		"audio":         fmt.Sprintf("data:audio/wav;base64,%s", audioBase64),
-		"model":         model.Name,
-		"sentenceCount": len(sentences),
-	}, http.StatusOK)
+		"audio":         fmt.Sprintf("data:%s;base64,%s", engine.OutputFormats[conv.Format].ContentType, audioBase64),
+		"format":        conv.Format,
+		"model":         conv.Model.Name,
+		"sentenceCount": len(conv.Items),
+		"segments":      result.Segments,
+	}
+
+	if result.Loudness != nil {
+		response["loudness"] = result.Loudness
+	}
+
+	if len(conv.Voices) > 0 {
+		response["voices"] = conv.Voices
+	}
+
+	if requestData.Subtitles != nil {
+		cues := buildSubtitleCues(result.Segments, *requestData.Subtitles)
+		response["subtitles"] = map[string]string{
+			"srt": formatSRT(cues),
+			"vtt": formatWebVTT(cues),
+		}
+	}
+
+	jsonResponse(w, response, http.StatusOK)
 }
 
 // GET /rescan-models - Rescan models
 func rescanModelsHandler(w http.ResponseWriter, r *http.Request) {
-	if err := scanModels(); err != nil {
+	if err := ttsEngine.Rescan(); err != nil {
 		errorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -191,19 +137,20 @@ func rescanModelsHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{
 		"success":    true,
 		"message":    "Models rescanned",
-		"modelCount": len(availableModels),
+		"modelCount": len(ttsEngine.Models()),
 	}, http.StatusOK)
 }
 
 // GET /settings - Get current settings
 func getSettingsHandler(w http.ResponseWriter, r *http.Request) {
-	queueStatus := processQueue.GetStatus()
+	queueStatus := ttsEngine.QueueStatus()
 
 	jsonResponse(w, map[string]interface{}{
 		"success": true,
 		"settings": map[string]interface{}{
 			"maxThreads":           userSettings.MaxThreads,
 			"autoDetectThreads":    userSettings.AutoDetectThreads,
+			"persistentWorkers":    ttsEngine.PersistentWorkers(),
 			"cpuCores":             cpuCores,
 			"currentMaxConcurrent": queueStatus.MaxConcurrent,
 			"recommendedThreads":   cpuCores * 2,
@@ -217,6 +164,7 @@ func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
 		MaxThreads        *int  `json:"maxThreads"`
 		AutoDetectThreads *bool `json:"autoDetectThreads"`
+		PersistentWorkers *bool `json:"persistentWorkers"`
 	}
 
 	body, err := io.ReadAll(r.Body)
@@ -234,6 +182,10 @@ func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		userSettings.AutoDetectThreads = *requestData.AutoDetectThreads
 	}
 
+	if requestData.PersistentWorkers != nil {
+		ttsEngine.SetPersistentWorkers(*requestData.PersistentWorkers)
+	}
+
 	if requestData.MaxThreads != nil && *requestData.MaxThreads > 0 {
 		maxThreads := *requestData.MaxThreads
 		if maxThreads < 1 {
@@ -245,18 +197,18 @@ func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		userSettings.MaxThreads = maxThreads
 
 		if !userSettings.AutoDetectThreads {
-			processQueue.SetMaxConcurrent(userSettings.MaxThreads)
+			ttsEngine.SetMaxConcurrent(userSettings.MaxThreads)
 		}
 	}
 
 	// If auto-detect is enabled, use CPU-based calculation
 	if userSettings.AutoDetectThreads {
 		autoThreads := cpuCores * 2
-		processQueue.SetMaxConcurrent(autoThreads)
+		ttsEngine.SetMaxConcurrent(autoThreads)
 		userSettings.MaxThreads = autoThreads
 	}
 
-	queueStatus := processQueue.GetStatus()
+	queueStatus := ttsEngine.QueueStatus()
 
 	jsonResponse(w, map[string]interface{}{
 		"success": true,
@@ -264,6 +216,7 @@ func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		"settings": map[string]interface{}{
 			"maxThreads":           userSettings.MaxThreads,
 			"autoDetectThreads":    userSettings.AutoDetectThreads,
+			"persistentWorkers":    ttsEngine.PersistentWorkers(),
 			"cpuCores":             cpuCores,
 			"currentMaxConcurrent": queueStatus.MaxConcurrent,
 		},
@@ -273,10 +226,388 @@ func updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /queue-status - Get queue status
 func getQueueStatusHandler(w http.ResponseWriter, r *http.Request) {
-	queueStatus := processQueue.GetStatus()
+	queueStatus := ttsEngine.QueueStatus()
+	workers, busyWorkers := ttsEngine.WorkerStats()
 
 	jsonResponse(w, map[string]interface{}{
 		"success": true,
 		"status":  queueStatus,
+		"workers": map[string]interface{}{
+			"enabled": ttsEngine.PersistentWorkers(),
+			"running": workers,
+			"busy":    busyWorkers,
+		},
+	}, http.StatusOK)
+}
+
+// POST /jobs - Start an asynchronous conversion
+func createJobHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData engine.ConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		errorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	conv, err := ttsEngine.Prepare(requestData)
+	if err != nil {
+		errorResponse(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	job := jobManager.Create(conv, requestClient(r))
+
+	jsonResponse(w, map[string]interface{}{
+		"success":   true,
+		"jobId":     job.ID,
+		"status":    job.Status,
+		"job":       job,
+		"statusUrl": "/jobs/" + job.ID,
+		"audioUrl":  "/jobs/" + job.ID + "/audio",
+	}, http.StatusAccepted)
+}
+
+// GET /jobs/{id} - Get job status and per-sentence progress
+func getJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := jobManager.Get(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"job":     job,
+	}, http.StatusOK)
+}
+
+// GET /jobs/{id}/audio - Download the audio of a completed job
+func getJobAudioHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	audioPath, format, status, ok := jobManager.AudioPath(id)
+	if !ok {
+		errorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if status != JobCompleted {
+		errorResponse(w, fmt.Sprintf("Job is %s", status), http.StatusConflict)
+		return
+	}
+
+	file, err := os.Open(audioPath)
+	if err != nil {
+		errorResponse(w, "Audio is no longer available", http.StatusGone)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	outputFormat := engine.OutputFormats[format]
+	filename := id + outputFormat.Extension
+
+	w.Header().Set("Content-Type", outputFormat.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	http.ServeContent(w, r, filename, info.ModTime(), file)
+}
+
+// GET /jobs/{id}/subtitles - Download captions for the audio of a completed job
+func getJobSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "srt"
+	}
+	contentType, ok := subtitleContentTypes[format]
+	if !ok {
+		errorResponse(w, "Format must be srt or vtt", http.StatusBadRequest)
+		return
+	}
+
+	options := SubtitleOptions{}
+	options.LineLength, _ = strconv.Atoi(query.Get("lineLength"))
+	options.MaxLines, _ = strconv.Atoi(query.Get("maxLines"))
+
+	job, ok := jobManager.Get(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if job.Status != JobCompleted {
+		errorResponse(w, fmt.Sprintf("Job is %s", job.Status), http.StatusConflict)
+		return
+	}
+
+	subtitles, err := formatSubtitles(format, buildSubtitleCues(job.Segments, options))
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", job.ID, format))
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, subtitles)
+}
+
+// DELETE /jobs/{id} - Cancel a running job or discard a finished one
+func deleteJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := jobManager.Cancel(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"job":     job,
+	}, http.StatusOK)
+}
+
+// POST /convert/stream - Stream audio in sentence order as each piece is synthesized
+func convertStreamHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "wav"
+	}
+	if format != "wav" && format != "pcm" {
+		errorResponse(w, "Format must be wav or pcm", http.StatusBadRequest)
+		return
+	}
+
+	var requestData engine.ConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		errorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	conv, err := ttsEngine.Prepare(requestData)
+	if err != nil {
+		errorResponse(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	headerWritten := false
+
+	err = ttsEngine.Stream(requestContext(r), conv, func(index int, buffer *audio.IntBuffer, header *engine.WAVHeader) error {
+		if !headerWritten {
+			if format == "wav" {
+				w.Header().Set("Content-Type", "audio/wav")
+			} else {
+				// Raw little-endian samples, described by the X-* headers below
+				w.Header().Set("Content-Type", "audio/pcm")
+			}
+			w.Header().Set("X-Sample-Rate", strconv.Itoa(int(header.SampleRate)))
+			w.Header().Set("X-Channels", strconv.Itoa(int(header.NumChannels)))
+			w.Header().Set("X-Bits-Per-Sample", strconv.Itoa(int(header.BitsPerSample)))
+			w.Header().Set("X-Sentence-Count", strconv.Itoa(len(conv.Items)))
+			w.WriteHeader(http.StatusOK)
+
+			if format == "wav" {
+				if err := engine.WriteStreamingWAVHeader(w, header); err != nil {
+					return err
+				}
+			}
+			headerWritten = true
+		}
+
+		if _, err := w.Write(engine.PCMBytes(buffer, int(header.BitsPerSample))); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("[STREAM] ❌ Streaming stopped: %v", err)
+		if !headerWritten {
+			errorResponse(w, err.Error(), errorStatus(err))
+		}
+		return
+	}
+
+	log.Printf("[STREAM] ✅ Streamed %d sentences", len(conv.Items))
+}
+
+// GET /lexicons - List the loaded lexicons and their number of entries
+func getLexiconsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, map[string]interface{}{
+		"success":  true,
+		"lexicons": ttsEngine.Lexicons().Languages(),
+	}, http.StatusOK)
+}
+
+// GET /lexicons/{lang} - Get the entries of a lexicon
+func getLexiconHandler(w http.ResponseWriter, r *http.Request) {
+	lang := mux.Vars(r)["lang"]
+
+	jsonResponse(w, map[string]interface{}{
+		"success":  true,
+		"language": engine.LexiconKey(lang),
+		"entries":  ttsEngine.Lexicons().Entries(lang),
+	}, http.StatusOK)
+}
+
+// POST /lexicons/{lang} - Add an entry to a lexicon
+func addLexiconEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var rule engine.ReplacementRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		errorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := ttsEngine.Lexicons().Add(mux.Vars(r)["lang"], rule)
+	if err != nil {
+		errorResponse(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"entry":   entry,
+	}, http.StatusCreated)
+}
+
+// PUT /lexicons/{lang}/{id} - Replace an entry of a lexicon
+func updateLexiconEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var rule engine.ReplacementRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		errorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	entry, err := ttsEngine.Lexicons().Update(vars["lang"], vars["id"], rule)
+	if err != nil {
+		errorResponse(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"entry":   entry,
+	}, http.StatusOK)
+}
+
+// DELETE /lexicons/{lang}/{id} - Remove an entry from a lexicon
+func deleteLexiconEntryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entry, err := ttsEngine.Lexicons().Delete(vars["lang"], vars["id"])
+	if err != nil {
+		errorResponse(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"entry":   entry,
+	}, http.StatusOK)
+}
+
+// Largest EPUB or HTML file accepted by /audiobooks
+const maxAudiobookUpload = 100 << 20
+
+// POST /audiobooks - Start one job per chapter of an uploaded EPUB or HTML file.
+// The multipart form has the book in "file" and the convert options (modelPath, format...) as JSON in "options".
+func createAudiobookHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudiobookUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		errorResponse(w, "Expected a multipart form with the book in 'file'", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		errorResponse(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var requestData engine.ConvertRequest
+	if options := r.FormValue("options"); options != "" {
+		if err := json.Unmarshal([]byte(options), &requestData); err != nil {
+			errorResponse(w, "Invalid options", http.StatusBadRequest)
+			return
+		}
+	}
+
+	title, chapters, conversions, status, err := prepareAudiobook(data, requestData)
+	if err != nil {
+		errorResponse(w, err.Error(), status)
+		return
+	}
+
+	book := audiobookManager.Create(title, chapters, conversions, requestClient(r))
+
+	jsonResponse(w, map[string]interface{}{
+		"success":   true,
+		"audiobook": book,
+		"statusUrl": "/audiobooks/" + book.ID,
+		"audioUrl":  "/audiobooks/" + book.ID + "/audio",
+	}, http.StatusAccepted)
+}
+
+// GET /audiobooks/{id} - Get the progress of every chapter
+func getAudiobookHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := audiobookManager.Get(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Audiobook not found", http.StatusNotFound)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success":   true,
+		"audiobook": book,
+	}, http.StatusOK)
+}
+
+// GET /audiobooks/{id}/audio - Download a zip with one audio file per chapter
+func getAudiobookAudioHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := audiobookManager.Get(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Audiobook not found", http.StatusNotFound)
+		return
+	}
+
+	if book.Status != JobCompleted {
+		errorResponse(w, fmt.Sprintf("Audiobook is %s", book.Status), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", safeFileName(book.Title)+".zip"))
+	if err := writeAudiobookZip(w, book); err != nil {
+		// Headers are already sent, so the client only sees a truncated zip
+		log.Printf("[AUDIOBOOK] ❌ Error writing zip for %s: %v", book.ID, err)
+	}
+}
+
+// DELETE /audiobooks/{id} - Cancel an audiobook, or discard it once finished
+func deleteAudiobookHandler(w http.ResponseWriter, r *http.Request) {
+	book, ok := audiobookManager.Cancel(mux.Vars(r)["id"])
+	if !ok {
+		errorResponse(w, "Audiobook not found", http.StatusNotFound)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success":   true,
+		"audiobook": book,
 	}, http.StatusOK)
 }
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopiper/engine"
+)
+
+// Send a request with a JSON body (when body isn't nil) to a fresh router
+func serveTestRequest(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	recorder := httptest.NewRecorder()
+	newRouter().ServeHTTP(recorder, httptest.NewRequest(method, path, reader))
+	return recorder
+}
+
+func decodeTestResponse(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", recorder.Body.String(), err)
+	}
+}
+
+func testModelPath(t *testing.T, id string) string {
+	t.Helper()
+	model, err := ttsEngine.FindModelByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.OnnxPath
+}
+
+// Check a 16-bit mono PCM WAV file at rate and return its length in seconds
+func checkWAVHeader(t *testing.T, data []byte, rate int) float64 {
+	t.Helper()
+
+	if len(data) < 44 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Fatalf("not a WAV file: % x", data[:min(len(data), 16)])
+	}
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size %d for %d bytes of file", size, len(data))
+	}
+
+	var dataSize int
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		chunk := data[offset+8:]
+
+		switch id {
+		case "fmt ":
+			format := binary.LittleEndian.Uint16(chunk[0:2])
+			channels := binary.LittleEndian.Uint16(chunk[2:4])
+			sampleRate := binary.LittleEndian.Uint32(chunk[4:8])
+			bits := binary.LittleEndian.Uint16(chunk[14:16])
+			if format != 1 || channels != 1 || int(sampleRate) != rate || bits != 16 {
+				t.Errorf("format %d, %d channels, %d Hz, %d bits; want PCM, mono, %d Hz, 16 bits", format, channels, sampleRate, bits, rate)
+			}
+		case "data":
+			dataSize = size
+			if offset+8+size != len(data) {
+				t.Errorf("data chunk of %d bytes doesn't end the file", size)
+			}
+		}
+		offset += 8 + size + size%2
+	}
+
+	if dataSize == 0 {
+		t.Fatal("no audio in the WAV file")
+	}
+	return float64(dataSize/2) / float64(rate)
+}
+
+// The test backend reads every character in 10 ms
+func expectedDuration(text string) float64 {
+	return float64(len([]rune(text))) * 0.01
+}
+
+func checkSegments(t *testing.T, segments []engine.Segment, sentences []string, total float64) {
+	t.Helper()
+
+	if len(segments) != len(sentences) {
+		t.Fatalf("got %d segments, want %d", len(segments), len(sentences))
+	}
+
+	previousEnd := 0.0
+	for i, segment := range segments {
+		if segment.Index != i || segment.Text != sentences[i] {
+			t.Errorf("segment %d is #%d %q, want %q", i, segment.Index, segment.Text, sentences[i])
+		}
+		if segment.Start < previousEnd {
+			t.Errorf("segment %d starts at %.3f, before the previous one ends at %.3f", i, segment.Start, previousEnd)
+		}
+		if duration := segment.End - segment.Start; math.Abs(duration-expectedDuration(sentences[i])) > 0.02 {
+			t.Errorf("segment %d lasts %.3f s, want about %.3f", i, duration, expectedDuration(sentences[i]))
+		}
+		previousEnd = segment.End
+	}
+
+	if math.Abs(previousEnd-total) > 0.01 {
+		t.Errorf("last segment ends at %.3f s of %.3f s of audio", previousEnd, total)
+	}
+}
+
+func TestConvertHandler(t *testing.T) {
+	setupTestEngine(t, nil)
+	sentences := []string{"The quick brown fox jumps over the lazy dog.", "How are you doing on this fine day?"}
+
+	recorder := serveTestRequest(t, http.MethodPost, "/convert", map[string]interface{}{
+		"text":      strings.Join(sentences, " "),
+		"modelPath": testModelPath(t, "en_US-test"),
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var response struct {
+		Success       bool             `json:"success"`
+		Audio         string           `json:"audio"`
+		Format        string           `json:"format"`
+		SentenceCount int              `json:"sentenceCount"`
+		Segments      []engine.Segment `json:"segments"`
+	}
+	decodeTestResponse(t, recorder, &response)
+
+	if !response.Success || response.Format != "wav" || response.SentenceCount != 2 {
+		t.Errorf("got success %v, format %q, %d sentences", response.Success, response.Format, response.SentenceCount)
+	}
+
+	prefix := "data:audio/wav;base64,"
+	if !strings.HasPrefix(response.Audio, prefix) {
+		t.Fatalf("audio starts with %.40q", response.Audio)
+	}
+	wav, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(response.Audio, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := checkWAVHeader(t, wav, 22050)
+	checkSegments(t, response.Segments, sentences, total)
+}
+
+func TestConvertHandlerErrors(t *testing.T) {
+	setupTestEngine(t, nil)
+
+	tests := []struct {
+		name   string
+		body   interface{}
+		status int
+	}{
+		{"invalid JSON", "{", http.StatusBadRequest},
+		{"no text", map[string]interface{}{"modelPath": testModelPath(t, "en_US-test")}, http.StatusBadRequest},
+		{"no model", map[string]interface{}{"text": "Hello."}, http.StatusBadRequest},
+		{"unknown model", map[string]interface{}{"text": "Hello.", "modelPath": "models/missing.onnx"}, http.StatusNotFound},
+		{"unknown format", map[string]interface{}{"text": "Hello.", "modelPath": testModelPath(t, "en_US-test"), "format": "aiff"}, http.StatusBadRequest},
+		{"nothing to read", map[string]interface{}{"text": "```\ncode\n```", "modelPath": testModelPath(t, "en_US-test")}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		recorder := serveTestRequest(t, http.MethodPost, "/convert", tt.body)
+		if recorder.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, recorder.Code, tt.status, recorder.Body)
+			continue
+		}
+
+		var response struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		decodeTestResponse(t, recorder, &response)
+		if response.Success || response.Error == "" {
+			t.Errorf("%s: response %s doesn't report the error", tt.name, recorder.Body)
+		}
+	}
+}
+
+// Post body to /convert/stream on a real server and return the response with its whole body
+func streamTestRequest(t *testing.T, query string, body interface{}) (*http.Response, []byte) {
+	t.Helper()
+
+	server := httptest.NewServer(newRouter())
+	defer server.Close()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+"/convert/stream"+query, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, content
+}
+
+func TestConvertStreamHandler(t *testing.T) {
+	setupTestEngine(t, nil)
+	sentences := []string{"The quick brown fox jumps over the lazy dog.", "How are you doing on this fine day?", "Streaming ends with this sentence."}
+	body := map[string]interface{}{
+		"text":      strings.Join(sentences, " "),
+		"modelPath": testModelPath(t, "en_US-test"),
+	}
+	expected := 0.0
+	for _, sentence := range sentences {
+		expected += expectedDuration(sentence)
+	}
+
+	resp, content := streamTestRequest(t, "", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, content)
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("transfer encoding %v, want chunked", resp.TransferEncoding)
+	}
+	if resp.Header.Get("Content-Type") != "audio/wav" || resp.Header.Get("X-Sentence-Count") != "3" {
+		t.Errorf("got headers %v", resp.Header)
+	}
+
+	// An open-ended header: the RIFF and data sizes aren't known when it is sent
+	if len(content) < 44 || string(content[0:4]) != "RIFF" || string(content[8:16]) != "WAVEfmt " || string(content[36:40]) != "data" {
+		t.Fatalf("not a WAV stream: % x", content[:min(len(content), 44)])
+	}
+	if binary.LittleEndian.Uint32(content[4:8]) != 0xFFFFFFFF || binary.LittleEndian.Uint32(content[40:44]) != 0xFFFFFFFF {
+		t.Errorf("stream header has sizes %d and %d", binary.LittleEndian.Uint32(content[4:8]), binary.LittleEndian.Uint32(content[40:44]))
+	}
+	format := binary.LittleEndian.Uint16(content[20:22])
+	channels := binary.LittleEndian.Uint16(content[22:24])
+	sampleRate := binary.LittleEndian.Uint32(content[24:28])
+	bits := binary.LittleEndian.Uint16(content[34:36])
+	if format != 1 || channels != 1 || sampleRate != 22050 || bits != 16 {
+		t.Errorf("format %d, %d channels, %d Hz, %d bits; want PCM, mono, 22050 Hz, 16 bits", format, channels, sampleRate, bits)
+	}
+
+	dataSize := len(content) - 44
+	if dataSize%2 != 0 {
+		t.Errorf("%d bytes of data aren't whole samples", dataSize)
+	}
+	if duration := float64(dataSize/2) / 22050; math.Abs(duration-expected) > 0.05 {
+		t.Errorf("%.3f s of audio, want about %.3f", duration, expected)
+	}
+
+	// Raw PCM, described by headers, at a requested rate
+	body["sampleRate"] = 16000
+	resp, content = streamTestRequest(t, "?format=pcm", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("pcm: status %d: %s", resp.StatusCode, content)
+	}
+	if resp.Header.Get("Content-Type") != "audio/pcm" || resp.Header.Get("X-Sample-Rate") != "16000" || resp.Header.Get("X-Channels") != "1" || resp.Header.Get("X-Bits-Per-Sample") != "16" {
+		t.Errorf("pcm: got headers %v", resp.Header)
+	}
+	if bytes.HasPrefix(content, []byte("RIFF")) {
+		t.Error("pcm stream has a WAV header")
+	}
+	if duration := float64(len(content)/2) / 16000; math.Abs(duration-expected) > 0.05 {
+		t.Errorf("pcm: %.3f s of audio, want about %.3f", duration, expected)
+	}
+}
+
+func TestConvertStreamHandlerErrors(t *testing.T) {
+	setupTestEngine(t, nil)
+	modelPath := testModelPath(t, "en_US-test")
+
+	tests := []struct {
+		name   string
+		query  string
+		body   map[string]interface{}
+		status int
+	}{
+		{"unknown stream format", "?format=ogg", map[string]interface{}{"text": "Hello.", "modelPath": modelPath}, http.StatusBadRequest},
+		{"no text", "", map[string]interface{}{"modelPath": modelPath}, http.StatusBadRequest},
+		{"unknown model", "", map[string]interface{}{"text": "Hello.", "modelPath": "models/missing.onnx"}, http.StatusNotFound},
+		{"encoded format", "", map[string]interface{}{"text": "Hello.", "modelPath": modelPath, "format": "flac"}, http.StatusBadRequest},
+		{"bitrate", "", map[string]interface{}{"text": "Hello.", "modelPath": modelPath, "bitrate": 128}, http.StatusBadRequest},
+		{"loudness", "", map[string]interface{}{"text": "Hello.", "modelPath": modelPath, "settings": map[string]interface{}{"target_lufs": -16.0}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		resp, content := streamTestRequest(t, tt.query, tt.body)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, resp.StatusCode, tt.status, content)
+		}
+		if !bytes.Contains(content, []byte(`"success":false`)) {
+			t.Errorf("%s: response %s doesn't report the error", tt.name, content)
+		}
+	}
+}
+
+func TestOpenAISpeechHandler(t *testing.T) {
+	setupTestEngine(t, nil)
+	text := "Hello from the speech endpoint."
+
+	recorder := serveTestRequest(t, http.MethodPost, "/v1/audio/speech", map[string]interface{}{
+		"model": "tts-1",
+		"input": text,
+		"voice": "en_US-test",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "audio/wav" {
+		t.Errorf("Content-Type %q", contentType)
+	}
+	if duration := checkWAVHeader(t, recorder.Body.Bytes(), 22050); math.Abs(duration-expectedDuration(text)) > 0.02 {
+		t.Errorf("%.3f s of audio, want about %.3f", duration, expectedDuration(text))
+	}
+
+	// Headerless 24 kHz 16-bit mono, at double speed
+	recorder = serveTestRequest(t, http.MethodPost, "/v1/audio/speech", map[string]interface{}{
+		"input":           text,
+		"voice":           "en_US-test",
+		"response_format": "pcm",
+		"speed":           2.0,
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("pcm: status %d: %s", recorder.Code, recorder.Body)
+	}
+	if bytes.HasPrefix(recorder.Body.Bytes(), []byte("RIFF")) {
+		t.Error("pcm response has a WAV header")
+	}
+	if duration := float64(recorder.Body.Len()/2) / 24000; math.Abs(duration-expectedDuration(text)/2) > 0.02 {
+		t.Errorf("pcm: %.3f s of audio at double speed, want about %.3f", duration, expectedDuration(text)/2)
+	}
+}
+
+func TestOpenAISpeechHandlerErrors(t *testing.T) {
+	setupTestEngine(t, nil)
+
+	tests := []struct {
+		name  string
+		body  interface{}
+		param string
+	}{
+		{"no input", map[string]interface{}{"voice": "en_US-test"}, "input"},
+		{"unknown voice", map[string]interface{}{"input": "Hi.", "voice": "alloy"}, "voice"},
+		{"speed", map[string]interface{}{"input": "Hi.", "voice": "en_US-test", "speed": 5.0}, "speed"},
+		{"format", map[string]interface{}{"input": "Hi.", "voice": "en_US-test", "response_format": "aiff"}, "response_format"},
+	}
+
+	for _, tt := range tests {
+		recorder := serveTestRequest(t, http.MethodPost, "/v1/audio/speech", tt.body)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", tt.name, recorder.Code, recorder.Body)
+			continue
+		}
+
+		var response struct {
+			Error struct {
+				Type  string `json:"type"`
+				Param string `json:"param"`
+			} `json:"error"`
+		}
+		decodeTestResponse(t, recorder, &response)
+		if response.Error.Type != "invalid_request_error" || response.Error.Param != tt.param {
+			t.Errorf("%s: error %+v, want an invalid_request_error on %s", tt.name, response.Error, tt.param)
+		}
+	}
+}
+
+// Poll path until the JSON field key reports a finished status
+func waitForTestStatus(t *testing.T, path, key string) map[string]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		recorder := serveTestRequest(t, http.MethodGet, path, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, recorder.Code, recorder.Body)
+		}
+
+		var response map[string]interface{}
+		decodeTestResponse(t, recorder, &response)
+		object := response[key].(map[string]interface{})
+		switch JobStatus(object["status"].(string)) {
+		case JobCompleted:
+			return object
+		case JobFailed, JobCancelled:
+			t.Fatalf("GET %s: %v", path, object)
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s: still %v", path, object["status"])
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestJobHandlers(t *testing.T) {
+	setupTestEngine(t, nil)
+	sentences := []string{"The first job sentence.", "And the second one."}
+
+	recorder := serveTestRequest(t, http.MethodPost, "/jobs", map[string]interface{}{
+		"text":      strings.Join(sentences, " "),
+		"modelPath": testModelPath(t, "en_US-test"),
+	})
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var created struct {
+		JobID    string `json:"jobId"`
+		AudioURL string `json:"audioUrl"`
+	}
+	decodeTestResponse(t, recorder, &created)
+	defer serveTestRequest(t, http.MethodDelete, "/jobs/"+created.JobID, nil)
+
+	job := waitForTestStatus(t, "/jobs/"+created.JobID, "job")
+	if job["progress"].(float64) != 1 || job["completed"].(float64) != 2 {
+		t.Errorf("completed job reports progress %v, %v sentences", job["progress"], job["completed"])
+	}
+
+	recorder = serveTestRequest(t, http.MethodGet, created.AudioURL, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("audio: status %d: %s", recorder.Code, recorder.Body)
+	}
+	total := checkWAVHeader(t, recorder.Body.Bytes(), 22050)
+
+	raw, _ := json.Marshal(job["segments"])
+	var segments []engine.Segment
+	json.Unmarshal(raw, &segments)
+	checkSegments(t, segments, sentences, total)
+
+	recorder = serveTestRequest(t, http.MethodGet, "/jobs/"+created.JobID+"/subtitles?format=vtt", nil)
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Body.String(), "WEBVTT") {
+		t.Errorf("subtitles: status %d: %.80s", recorder.Code, recorder.Body)
+	}
+
+	for _, path := range []string{"/jobs/missing", "/jobs/missing/audio"} {
+		if recorder := serveTestRequest(t, http.MethodGet, path, nil); recorder.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, recorder.Code)
+		}
+	}
+
+	recorder = serveTestRequest(t, http.MethodPost, "/jobs", map[string]interface{}{"text": "No model."})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("job without a model: status %d, want 400", recorder.Code)
+	}
+}
+
+// Synthesizes like a FakeBackend once release is closed
+type gatedBackend struct {
+	engine.FakeBackend
+	release chan struct{}
+}
+
+func (gb *gatedBackend) Synthesize(ctx context.Context, text, modelPath string, settings engine.AudioSettings) (string, error) {
+	select {
+	case <-gb.release:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return gb.FakeBackend.Synthesize(ctx, text, modelPath, settings)
+}
+
+// Create a job and return its ID
+func createTestJob(t *testing.T, body map[string]interface{}) string {
+	t.Helper()
+
+	recorder := serveTestRequest(t, http.MethodPost, "/jobs", body)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	var created struct {
+		JobID  string    `json:"jobId"`
+		Status JobStatus `json:"status"`
+	}
+	decodeTestResponse(t, recorder, &created)
+	if created.Status != JobQueued {
+		t.Errorf("new job is %s, want %s", created.Status, JobQueued)
+	}
+	t.Cleanup(func() { serveTestRequest(t, http.MethodDelete, "/jobs/"+created.JobID, nil) })
+	return created.JobID
+}
+
+func TestJobQueuedUntilStarted(t *testing.T) {
+	backend := &gatedBackend{
+		FakeBackend: engine.FakeBackend{Frequency: 440, CharDuration: 10 * time.Millisecond},
+		release:     make(chan struct{}),
+	}
+	setupTestEngine(t, backend)
+	ttsEngine.SetMaxConcurrent(1)
+
+	// The first job takes the only slot, so the second one waits behind it
+	first := createTestJob(t, map[string]interface{}{"text": "The first job.", "modelPath": testModelPath(t, "en_US-test")})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := jobManager.Get(first)
+		if job.Status == JobRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("first job is still %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	second := createTestJob(t, map[string]interface{}{"text": "The second job.", "modelPath": testModelPath(t, "en_US-test")})
+	time.Sleep(50 * time.Millisecond)
+	if job, _ := jobManager.Get(second); job.Status != JobQueued {
+		t.Errorf("waiting job is %s, want %s", job.Status, JobQueued)
+	}
+
+	close(backend.release)
+	waitForTestStatus(t, "/jobs/"+first, "job")
+	waitForTestStatus(t, "/jobs/"+second, "job")
+}
+
+func TestJobCountsSourceSentences(t *testing.T) {
+	setupTestEngine(t, nil)
+
+	// Over 400 characters without a sentence end, so it is synthesized in pieces
+	long := strings.Repeat("esta frase sigue y sigue, cuando nadie la detiene ", 10) + "hasta aquí."
+	sentences := []string{long, "¿Se lee esta parte del texto tal como está escrita?"}
+	id := createTestJob(t, map[string]interface{}{"text": strings.Join(sentences, " "), "modelPath": testModelPath(t, "es_MX-test")})
+
+	waitForTestStatus(t, "/jobs/"+id, "job")
+	job, _ := jobManager.Get(id)
+	if job.SentenceCount != 2 || len(job.Sentences) != 2 || job.Completed != 2 || len(job.Segments) != 2 {
+		t.Fatalf("got %d sentences (%d listed, %d completed) and %d segments, want 2 of each",
+			job.SentenceCount, len(job.Sentences), job.Completed, len(job.Segments))
+	}
+	for i, sentence := range job.Sentences {
+		if sentence.Index != i || sentence.Status != "done" || sentence.Text != strings.TrimSpace(sentences[i]) {
+			t.Errorf("sentence %d is %+v", i, sentence)
+		}
+	}
+}
+
+func TestAudiobookHandlers(t *testing.T) {
+	setupTestEngine(t, nil)
+
+	book := `<html><head><title>Test Book</title></head><body>
+<h1>One</h1><p>The first chapter is short.</p>
+<h1>Empty</h1>
+<h1>Two</h1><p>The second chapter is short too.</p>
+</body></html>`
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "book.html")
+	file.Write([]byte(book))
+	options, _ := json.Marshal(map[string]interface{}{"modelPath": testModelPath(t, "en_US-test")})
+	form.WriteField("options", string(options))
+	form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/audiobooks", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	newRouter().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var created struct {
+		Audiobook Audiobook `json:"audiobook"`
+		AudioURL  string    `json:"audioUrl"`
+	}
+	decodeTestResponse(t, recorder, &created)
+	defer serveTestRequest(t, http.MethodDelete, "/audiobooks/"+created.Audiobook.ID, nil)
+
+	if created.Audiobook.Title != "Test Book" || len(created.Audiobook.Chapters) != 2 {
+		t.Errorf("got %q with %d chapters, want Test Book with 2 (the empty one left out)", created.Audiobook.Title, len(created.Audiobook.Chapters))
+	}
+
+	waitForTestStatus(t, "/audiobooks/"+created.Audiobook.ID, "audiobook")
+
+	recorder = serveTestRequest(t, http.MethodGet, created.AudioURL, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("audio: status %d: %s", recorder.Code, recorder.Body)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNames := []string{"01 One.wav", "02 Two.wav"}
+	if len(archive.File) != len(wantNames) {
+		t.Fatalf("zip has %d files, want %d", len(archive.File), len(wantNames))
+	}
+	for i, entry := range archive.File {
+		if entry.Name != wantNames[i] {
+			t.Errorf("zip entry %d is %q, want %q", i, entry.Name, wantNames[i])
+		}
+		reader, err := entry.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wav bytes.Buffer
+		wav.ReadFrom(reader)
+		reader.Close()
+		checkWAVHeader(t, wav.Bytes(), 22050)
+	}
+
+	if recorder := serveTestRequest(t, http.MethodGet, "/audiobooks/missing", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown audiobook: status %d, want 404", recorder.Code)
+	}
+	if recorder := serveTestRequest(t, http.MethodPost, "/audiobooks", "not a form"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("request without a form: status %d, want 400", recorder.Code)
+	}
+}
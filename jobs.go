@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gopiper/engine"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// How long finished jobs (and their audio) are kept before being discarded
+const jobRetention = 30 * time.Minute
+
+type SentenceProgress struct {
+	Index    int    `json:"index"`
+	Text     string `json:"text"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+
+	// Pieces of the sentence still being synthesized
+	pieces int
+}
+
+type Job struct {
+	ID            string                 `json:"id"`
+	Status        JobStatus              `json:"status"`
+	Model         string                 `json:"model"`
+	ModelPath     string                 `json:"modelPath"`
+	Format        string                 `json:"format"`
+	SentenceCount int                    `json:"sentenceCount"`
+	Completed     int                    `json:"completed"`
+	Progress      float64                `json:"progress"`
+	Sentences     []SentenceProgress     `json:"sentences"`
+	Segments      []engine.Segment       `json:"segments,omitempty"`
+	Loudness      *engine.LoudnessReport `json:"loudness,omitempty"`
+	Voices        map[string]string      `json:"voices,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	FinishedAt    *time.Time             `json:"finishedAt,omitempty"`
+
+	audioPath string
+	// The sentence each item of the conversion belongs to
+	sentenceOf []int
+	cancel     context.CancelFunc
+}
+
+type JobManager struct {
+	jobs map[string]*Job
+	mu   sync.Mutex
+}
+
+var jobManager = NewJobManager()
+
+func NewJobManager() *JobManager {
+	jm := &JobManager{
+		jobs: make(map[string]*Job),
+	}
+
+	go jm.janitor()
+	return jm
+}
+
+// Create a job for a prepared conversion and start it in the background.
+// Its sentences are queued as batch work of client, behind interactive requests;
+// the job stays queued until the first of them starts.
+func (jm *JobManager) Create(conv *engine.Conversion, client string) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		ID:         generateRandomString(8),
+		Status:     JobQueued,
+		Model:      conv.Model.Name,
+		ModelPath:  conv.Model.OnnxPath,
+		Format:     conv.Format,
+		Voices:     conv.Voices,
+		Sentences:  []SentenceProgress{},
+		CreatedAt:  time.Now(),
+		sentenceOf: make([]int, len(conv.Items)),
+		cancel:     cancel,
+	}
+	// Sentences too long to read at once are synthesized in several pieces, but count
+	// as one sentence, like their segment
+	for i, item := range conv.Items {
+		if !item.Continued || len(job.Sentences) == 0 {
+			job.Sentences = append(job.Sentences, SentenceProgress{Index: len(job.Sentences), Text: item.SourceText, Status: "pending"})
+		}
+		job.sentenceOf[i] = len(job.Sentences) - 1
+		job.Sentences[len(job.Sentences)-1].pieces++
+	}
+	job.SentenceCount = len(job.Sentences)
+
+	ctx = engine.WithClient(engine.WithPriority(ctx, engine.PriorityBatch), client)
+	ctx = engine.WithStartHook(ctx, func() {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		job.start()
+	})
+
+	jm.mu.Lock()
+	jm.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	jm.mu.Unlock()
+
+	log.Printf("[JOBS] 📥 Created job %s with %d sentences", job.ID, job.SentenceCount)
+
+	go jm.run(ctx, job, conv)
+	return snapshot
+}
+
+func (jm *JobManager) run(ctx context.Context, job *Job, conv *engine.Conversion) {
+	result, err := ttsEngine.Render(ctx, conv, func(result engine.SentenceResult) {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+
+		// Cached sentences finish without going through the queue
+		job.start()
+
+		sentence := &job.Sentences[job.sentenceOf[result.Index]]
+		if result.Error != nil {
+			sentence.Status = "failed"
+			sentence.Error = result.Error.Error()
+			var sentenceErr *engine.SentenceError
+			if errors.As(result.Error, &sentenceErr) {
+				sentence.Error = sentenceErr.Err.Error()
+				sentence.Attempts = sentenceErr.Attempts
+				sentence.Stderr = sentenceErr.Stderr()
+			}
+			return
+		}
+
+		sentence.pieces--
+		if sentence.pieces > 0 || sentence.Status == "failed" {
+			return
+		}
+		sentence.Status = "done"
+		job.Completed++
+		job.Progress = float64(job.Completed) / float64(job.SentenceCount)
+	})
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+
+	switch {
+	case job.Status == JobCancelled:
+		// Cancelled while the last sentences were finishing
+		if err == nil {
+			os.Remove(result.AudioPath)
+		}
+		log.Printf("[JOBS] 🛑 Job %s cancelled", job.ID)
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		log.Printf("[JOBS] ❌ Job %s failed: %v", job.ID, err)
+	default:
+		job.Status = JobCompleted
+		job.Progress = 1
+		job.audioPath = result.AudioPath
+		job.Segments = result.Segments
+		job.Loudness = result.Loudness
+		log.Printf("[JOBS] ✅ Job %s completed", job.ID)
+	}
+}
+
+// Get a copy of a job
+func (jm *JobManager) Get(id string) (Job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.snapshot(), true
+}
+
+// Get the audio file of a completed job and its format
+func (jm *JobManager) AudioPath(id string) (string, string, JobStatus, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return "", "", "", false
+	}
+	return job.audioPath, job.Format, job.Status, true
+}
+
+// Cancel a running job, or discard a finished one together with its audio
+func (jm *JobManager) Cancel(id string) (Job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	switch job.Status {
+	case JobQueued, JobRunning:
+		job.Status = JobCancelled
+		job.cancel()
+		log.Printf("[JOBS] 🛑 Cancelling job %s", job.ID)
+	default:
+		jm.remove(job)
+	}
+
+	return job.snapshot(), true
+}
+
+// Remove every job and its audio file
+func (jm *JobManager) Close() {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	for _, job := range jm.jobs {
+		job.cancel()
+		jm.remove(job)
+	}
+}
+
+// Periodically drop finished jobs older than jobRetention
+func (jm *JobManager) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		jm.mu.Lock()
+		for _, job := range jm.jobs {
+			if job.FinishedAt != nil && time.Since(*job.FinishedAt) > jobRetention {
+				log.Printf("[JOBS] 🧹 Expiring job %s", job.ID)
+				jm.remove(job)
+			}
+		}
+		jm.mu.Unlock()
+	}
+}
+
+// remove must be called with jm.mu held
+func (jm *JobManager) remove(job *Job) {
+	if job.audioPath != "" {
+		os.Remove(job.audioPath)
+		job.audioPath = ""
+	}
+	delete(jm.jobs, job.ID)
+}
+
+// Mark a queued job as running; must be called with jm.mu held
+func (j *Job) start() {
+	if j.Status == JobQueued {
+		j.Status = JobRunning
+		log.Printf("[JOBS] ▶️  Job %s started", j.ID)
+	}
+}
+
+// snapshot must be called with jm.mu held
+func (j *Job) snapshot() Job {
+	copied := *j
+	copied.Sentences = make([]SentenceProgress, len(j.Sentences))
+	copy(copied.Sentences, j.Sentences)
+	return copied
+}
+
+// Random hex string of length bytes, used as the ID of jobs and audiobooks
+func generateRandomString(length int) string {
+	bytes := make([]byte, length)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
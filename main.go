@@ -3,8 +3,12 @@ package main
 //go:generate go run install_piper.go
 
 import (
+	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
@@ -23,6 +27,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/common-nighthawk/go-figure"
 	"github.com/joho/godotenv"
+
+	"gopiper/engine"
 )
 
 //go:embed piper
@@ -32,14 +38,10 @@ var piperFS embed.FS
 var webFS embed.FS
 
 var (
-	modelPaths      []string
-	availableModels []Model
-	piperPath       string
-	tempPiperDir    string
-	processQueue    *ProcessQueue
-	userSettings    Settings
-	cpuCores        int
-	maxTextLength   int = 0 // 0 means no limit
+	ttsEngine    *engine.Engine
+	tempPiperDir string
+	userSettings Settings
+	cpuCores     int
 )
 
 type Settings struct {
@@ -63,29 +65,68 @@ func main() {
 	// Setup cleanup on exit
 	setupCleanup()
 
-	// Initialize process queue
 	maxConcurrent := cpuCores * 2
-	processQueue = NewProcessQueue(maxConcurrent)
-	
 	userSettings = Settings{
 		MaxThreads:        maxConcurrent,
 		AutoDetectThreads: true,
 	}
 
+	config := engine.Config{
+		LibraryDir:    tempPiperDir,
+		MaxConcurrent: maxConcurrent,
+	}
+
 	// Initialize paths
-	initializePaths()
+	config.PiperPath = initializePaths()
 
 	// Initialize model paths
-	if err := initializeModelPaths(); err != nil {
+	modelPaths, err := initializeModelPaths()
+	if err != nil {
 		log.Printf("[MODELS] Warning: %v", err)
 	}
+	config.ModelPaths = modelPaths
+
+	// Load environment variables
+	loadEnv(&config)
+
+	// Initialize sentence audio cache
+	initializeSentenceCache(&config)
 
-	// Scan models
-	if err := scanModels(); err != nil {
-		log.Printf("[SCAN] Warning: %v", err)
+	// Start the engine, which scans the models
+	ttsEngine, err = engine.New(config)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Setup router
+	router := newRouter()
+
+	// Start server
+	port := getEnv("PORT", "3000")
+	host := getEnv("HOST", "127.0.0.1")
+
+	// Optional Wyoming listener for Home Assistant voice pipelines
+	if wyomingPort := getEnv("WYOMING_PORT", ""); wyomingPort != "" {
+		if err := startWyomingServer(host + ":" + wyomingPort); err != nil {
+			log.Printf("[WYOMING] ⚠️  Could not start Wyoming server: %v", err)
+		}
+	}
+	
+	// Display stylized banner
+	fmt.Println()
+	myFigure := figure.NewFigure("GoPiper", "", true)
+	myFigure.Print()
+	fmt.Println()
+	
+	// Try to start server with port availability checking
+	if err := startServer(router, host, port); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Build the router with every API route and the embedded web interface.
+// Handlers use ttsEngine, which must be set first.
+func newRouter() *mux.Router {
 	router := mux.NewRouter()
 	
 	// Enable CORS
@@ -95,10 +136,26 @@ func main() {
 	router.HandleFunc("/models", getModelsHandler).Methods("GET")
 	router.HandleFunc("/set-model-paths", setModelPathsHandler).Methods("POST")
 	router.HandleFunc("/convert", convertHandler).Methods("POST")
+	router.HandleFunc("/convert/stream", convertStreamHandler).Methods("POST")
 	router.HandleFunc("/rescan-models", rescanModelsHandler).Methods("GET")
 	router.HandleFunc("/settings", getSettingsHandler).Methods("GET")
 	router.HandleFunc("/settings", updateSettingsHandler).Methods("POST")
 	router.HandleFunc("/queue-status", getQueueStatusHandler).Methods("GET")
+	router.HandleFunc("/v1/audio/speech", openAISpeechHandler).Methods("POST")
+	router.HandleFunc("/jobs", createJobHandler).Methods("POST")
+	router.HandleFunc("/jobs/{id}", getJobHandler).Methods("GET")
+	router.HandleFunc("/jobs/{id}", deleteJobHandler).Methods("DELETE")
+	router.HandleFunc("/jobs/{id}/audio", getJobAudioHandler).Methods("GET")
+	router.HandleFunc("/jobs/{id}/subtitles", getJobSubtitlesHandler).Methods("GET")
+	router.HandleFunc("/lexicons", getLexiconsHandler).Methods("GET")
+	router.HandleFunc("/lexicons/{lang}", getLexiconHandler).Methods("GET")
+	router.HandleFunc("/lexicons/{lang}", addLexiconEntryHandler).Methods("POST")
+	router.HandleFunc("/lexicons/{lang}/{id}", updateLexiconEntryHandler).Methods("PUT")
+	router.HandleFunc("/lexicons/{lang}/{id}", deleteLexiconEntryHandler).Methods("DELETE")
+	router.HandleFunc("/audiobooks", createAudiobookHandler).Methods("POST")
+	router.HandleFunc("/audiobooks/{id}", getAudiobookHandler).Methods("GET")
+	router.HandleFunc("/audiobooks/{id}", deleteAudiobookHandler).Methods("DELETE")
+	router.HandleFunc("/audiobooks/{id}/audio", getAudiobookAudioHandler).Methods("GET")
 	
 	// Serve static files from embedded web directory
 	webSubFS, err := fs.Sub(webFS, "web")
@@ -108,30 +165,14 @@ func main() {
 	fileServer := http.FileServer(http.FS(webSubFS))
 	router.PathPrefix("/").Handler(fileServer)
 
-	// Load environment variables
-	loadEnv()
-	
-	// Start server
-	port := getEnv("PORT", "3000")
-	host := getEnv("HOST", "127.0.0.1")
-	
-	// Display stylized banner
-	fmt.Println()
-	myFigure := figure.NewFigure("GoPiper", "", true)
-	myFigure.Print()
-	fmt.Println()
-	
-	// Try to start server with port availability checking
-	if err := startServer(router, host, port); err != nil {
-		log.Fatal(err)
-	}
+	return router
 }
 
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -256,6 +297,11 @@ func setupCleanup() {
 
 // Cleanup temporary files
 func cleanup() {
+	jobManager.Close()
+	if ttsEngine != nil {
+		ttsEngine.Close()
+	}
+
 	if tempPiperDir != "" {
 		log.Printf("[CLEANUP] 🧹 Removing temporary piper directory: %s", tempPiperDir)
 		if err := os.RemoveAll(tempPiperDir); err != nil {
@@ -266,7 +312,8 @@ func cleanup() {
 	}
 }
 
-func initializePaths() {
+// Find the piper executable, extracted or next to the server
+func initializePaths() string {
 	// Use temp directory if piper was extracted, otherwise use local
 	var piperDir string
 	
@@ -285,6 +332,7 @@ func initializePaths() {
 	}
 
 	// Set paths based on OS
+	var piperPath string
 	if runtime.GOOS == "windows" {
 		piperPath = filepath.Join(piperDir, "piper.exe")
 	} else {
@@ -300,10 +348,11 @@ func initializePaths() {
 	} else {
 		log.Printf("[PATHS] ✅ Piper executable found")
 	}
+	return piperPath
 }
 
-func initializeModelPaths() error {
-	modelPaths = []string{}
+func initializeModelPaths() ([]string, error) {
+	modelPaths := []string{}
 
 	// Check local ./models directory
 	localModelsPath := filepath.Join(".", "models")
@@ -317,7 +366,7 @@ func initializeModelPaths() error {
 	// Add Documents path
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("error getting home directory: %v", err)
+		return modelPaths, fmt.Errorf("error getting home directory: %v", err)
 	}
 
 	onnxTtsPath := filepath.Join(homeDir, "Documents", "onnx-tts")
@@ -331,7 +380,7 @@ func initializeModelPaths() error {
 	}
 
 	log.Printf("[MODELS] Initialized model paths: %v", modelPaths)
-	return nil
+	return modelPaths, nil
 }
 
 // Response helpers
@@ -348,8 +397,67 @@ func errorResponse(w http.ResponseWriter, message string, statusCode int) {
 	}, statusCode)
 }
 
-// Load environment variables from .env file
-func loadEnv() {
+// HTTP status for an error of the engine
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, engine.ErrInvalidRequest), errors.Is(err, engine.ErrNoSentences):
+		return http.StatusBadRequest
+	case errors.Is(err, engine.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, engine.ErrReadOnly):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Identify who sent a request, so the queue can take turns between clients:
+// by API key when one is given (hashed, to keep it out of the logs), otherwise by address
+func requestClient(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:4])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Context of an interactive request, cancelled when the client disconnects
+func requestContext(r *http.Request) context.Context {
+	return engine.WithClient(r.Context(), requestClient(r))
+}
+
+// Report a failed conversion, with the sentence that failed and what piper printed
+func renderErrorResponse(w http.ResponseWriter, err error) {
+	var sentenceErr *engine.SentenceError
+	if !errors.As(err, &sentenceErr) {
+		errorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"success": false,
+		"error":   err.Error(),
+		"failedSentence": map[string]interface{}{
+			"index":    sentenceErr.Index,
+			"text":     sentenceErr.Sentence,
+			"attempts": sentenceErr.Attempts,
+			"reason":   sentenceErr.Err.Error(),
+			"stderr":   sentenceErr.Stderr(),
+		},
+	}, http.StatusInternalServerError)
+}
+
+// Load environment variables from .env file, applying the engine settings to config
+func loadEnv(config *engine.Config) {
 	// Try to load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Printf("[ENV] ⚠️  No .env file found, using defaults: %v", err)
@@ -360,12 +468,89 @@ func loadEnv() {
 	// Load MAX_TEXT if set
 	if maxTextStr := os.Getenv("MAX_TEXT"); maxTextStr != "" {
 		if maxText, err := strconv.Atoi(maxTextStr); err == nil {
-			maxTextLength = maxText
-			log.Printf("[ENV] ✅ Max text length set to %d characters", maxTextLength)
+			config.MaxTextLength = maxText
+			log.Printf("[ENV] ✅ Max text length set to %d characters", maxText)
 		} else {
 			log.Printf("[ENV] ⚠️  Invalid MAX_TEXT value: %s", maxTextStr)
 		}
 	}
+
+	// Load PIPER_WORKERS if set (keep piper processes alive between sentences)
+	if workersStr := os.Getenv("PIPER_WORKERS"); workersStr != "" {
+		if enabled, err := strconv.ParseBool(workersStr); err == nil {
+			config.PersistentWorkers = enabled
+			log.Printf("[ENV] ✅ Persistent piper workers: %v", enabled)
+		} else {
+			log.Printf("[ENV] ⚠️  Invalid PIPER_WORKERS value: %s", workersStr)
+		}
+	}
+
+	// Load SYNTHESIS_BACKEND if set ("fake" writes tones instead of running piper)
+	switch backend := os.Getenv("SYNTHESIS_BACKEND"); backend {
+	case "", "piper":
+	case "fake":
+		config.Backend = &engine.FakeBackend{Frequency: 440}
+		log.Printf("[ENV] ✅ Using the fake synthesis backend, piper won't be run")
+	default:
+		log.Printf("[ENV] ⚠️  Invalid SYNTHESIS_BACKEND value: %s", backend)
+	}
+
+	// Load WORKER_IDLE_TIMEOUT (seconds) if set
+	if idleStr := os.Getenv("WORKER_IDLE_TIMEOUT"); idleStr != "" {
+		if idle, err := strconv.Atoi(idleStr); err == nil && idle > 0 {
+			config.WorkerIdleTimeout = time.Duration(idle) * time.Second
+			log.Printf("[ENV] ✅ Idle piper workers stop after %v", config.WorkerIdleTimeout)
+		} else {
+			log.Printf("[ENV] ⚠️  Invalid WORKER_IDLE_TIMEOUT value: %s", idleStr)
+		}
+	}
+
+	// Load SENTENCE_TIMEOUT (seconds) if set
+	if timeoutStr := os.Getenv("SENTENCE_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil && timeout > 0 {
+			config.SentenceTimeout = time.Duration(timeout) * time.Second
+			log.Printf("[ENV] ✅ Sentences time out after %v", config.SentenceTimeout)
+		} else {
+			log.Printf("[ENV] ⚠️  Invalid SENTENCE_TIMEOUT value: %s", timeoutStr)
+		}
+	}
+
+	// Load SENTENCE_RETRIES if set; 0 turns retries off
+	if retriesStr := os.Getenv("SENTENCE_RETRIES"); retriesStr != "" {
+		if retries, err := strconv.Atoi(retriesStr); err == nil && retries >= 0 {
+			config.SentenceRetries = retries
+			if retries == 0 {
+				config.SentenceRetries = -1
+			}
+			log.Printf("[ENV] ✅ Failed sentences are retried %d time(s)", retries)
+		} else {
+			log.Printf("[ENV] ⚠️  Invalid SENTENCE_RETRIES value: %s", retriesStr)
+		}
+	}
+}
+
+// Set up the on-disk sentence cache (CACHE_DIR, CACHE_MAX_MB=0 disables it)
+func initializeSentenceCache(config *engine.Config) {
+	maxMB := 512
+	if maxMBStr := os.Getenv("CACHE_MAX_MB"); maxMBStr != "" {
+		if value, err := strconv.Atoi(maxMBStr); err == nil && value >= 0 {
+			maxMB = value
+		} else {
+			log.Printf("[ENV] ⚠️  Invalid CACHE_MAX_MB value: %s", maxMBStr)
+		}
+	}
+
+	cacheDir := os.Getenv("CACHE_DIR")
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			userCacheDir = os.TempDir()
+		}
+		cacheDir = filepath.Join(userCacheDir, "gopiper", "sentences")
+	}
+
+	config.CacheDir = cacheDir
+	config.CacheMaxBytes = int64(maxMB) * 1024 * 1024
 }
 
 // Get environment variable with default value
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopiper/engine"
+)
+
+// Point the server at an engine that synthesizes with backend (a FakeBackend when nil),
+// with an English and a Spanish model. Returns the directory of the models.
+func setupTestEngine(t *testing.T, backend engine.Backend) string {
+	t.Helper()
+
+	if backend == nil {
+		backend = &engine.FakeBackend{Frequency: 440, CharDuration: 10 * time.Millisecond}
+	}
+
+	modelDir := t.TempDir()
+	models := map[string]string{
+		"en_US-test-medium": `{"modelcard": {"id": "en_US-test", "name": "Test English", "language": "en"}}`,
+		"es_MX-test-medium": `{"modelcard": {"id": "es_MX-test", "name": "Test Spanish", "language": "es"}}`,
+	}
+	for name, card := range models {
+		if err := os.WriteFile(filepath.Join(modelDir, name+".onnx"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modelDir, name+".onnx.json"), []byte(card), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tts, err := engine.New(engine.Config{
+		Backend:       backend,
+		ModelPaths:    []string{modelDir},
+		MaxConcurrent: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttsEngine = tts
+	t.Cleanup(func() {
+		tts.Close()
+		ttsEngine = nil
+	})
+
+	return modelDir
+}
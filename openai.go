@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"gopiper/engine"
+)
+
+// Request body of the OpenAI text-to-speech API
+type OpenAISpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format"`
+	Speed          float64 `json:"speed"`
+}
+
+// POST /v1/audio/speech - OpenAI-compatible text to speech
+func openAISpeechHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData OpenAISpeechRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		openAIErrorResponse(w, "Invalid request body", "", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[OPENAI] 🎤 Speech request - voice: %s, model: %s, format: %s", requestData.Voice, requestData.Model, requestData.ResponseFormat)
+
+	if requestData.Input == "" {
+		openAIErrorResponse(w, "Input is required", "input", http.StatusBadRequest)
+		return
+	}
+
+	// OpenAI defaults to mp3, but that needs an external encoder here, so WAV stays the default
+	format := requestData.ResponseFormat
+	if format == "" {
+		format = "wav"
+	}
+
+	// pcm is cut out of a WAV; every other format goes through the regular encoders
+	outputFormat := format
+	if format == "pcm" {
+		outputFormat = "wav"
+	}
+	outputFormat, bitrate, err := engine.ResolveOutputFormat(outputFormat, 0)
+	if err != nil {
+		openAIErrorResponse(w, err.Error(), "response_format", http.StatusBadRequest)
+		return
+	}
+
+	contentType := engine.OutputFormats[outputFormat].ContentType
+	convertRequest := engine.ConvertRequest{
+		Text:    requestData.Input,
+		Options: engine.Options{Format: outputFormat, Bitrate: bitrate},
+	}
+	if format == "pcm" {
+		contentType = "audio/pcm"
+		// OpenAI's pcm is headerless 24 kHz 16-bit mono, and clients hardcode that
+		convertRequest.SampleRate = 24000
+		convertRequest.Channels = 1
+		convertRequest.BitsPerSample = 16
+	}
+
+	speed := requestData.Speed
+	if speed == 0 {
+		speed = 1.0
+	}
+	if speed < 0.25 || speed > 4.0 {
+		openAIErrorResponse(w, "Speed must be between 0.25 and 4.0", "speed", http.StatusBadRequest)
+		return
+	}
+
+	// The voice selects the model; fall back to the model field for clients that
+	// put the voice ID there and send a stock voice name. "auto" picks it by language.
+	if requestData.Voice == engine.AutoModelPath {
+		convertRequest.ModelPath = engine.AutoModelPath
+	} else {
+		model, err := ttsEngine.FindModelByID(requestData.Voice)
+		if err != nil && requestData.Model != "" {
+			model, err = ttsEngine.FindModelByID(requestData.Model)
+		}
+		if err != nil {
+			openAIErrorResponse(w, fmt.Sprintf("Voice not found: %s", requestData.Voice), "voice", http.StatusBadRequest)
+			return
+		}
+		convertRequest.ModelPath = model.OnnxPath
+	}
+
+	convertRequest.Settings = map[string]interface{}{
+		"length_scale": engine.DefaultAudioSettings().LengthScale / speed,
+	}
+
+	conv, err := ttsEngine.Prepare(convertRequest)
+	if err != nil {
+		openAIErrorResponse(w, err.Error(), "input", errorStatus(err))
+		return
+	}
+
+	result, err := ttsEngine.Render(requestContext(r), conv, nil)
+	if err != nil {
+		log.Printf("[OPENAI] ❌ Error generating audio: %v", err)
+		openAIErrorResponse(w, err.Error(), "", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(result.AudioPath)
+
+	var audioData []byte
+	if format == "pcm" {
+		buffer, header, err := engine.ReadWAVFile(result.AudioPath)
+		if err == nil {
+			audioData = engine.PCMBytes(buffer, int(header.BitsPerSample))
+		}
+	} else {
+		audioData, err = os.ReadFile(result.AudioPath)
+	}
+	if err != nil {
+		openAIErrorResponse(w, err.Error(), "", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[OPENAI] ✅ Speech completed with model %s (%dKB %s)", conv.Model.Name, len(audioData)/1024, format)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(audioData)
+}
+
+// Write an error in the OpenAI API error format
+func openAIErrorResponse(w http.ResponseWriter, message, param string, statusCode int) {
+	errorType := "invalid_request_error"
+	if statusCode >= 500 {
+		errorType = "server_error"
+	}
+
+	var paramValue interface{}
+	if param != "" {
+		paramValue = param
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"param":   paramValue,
+			"code":    nil,
+		},
+	}, statusCode)
+}
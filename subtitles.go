@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopiper/engine"
+)
+
+// Defaults follow common captioning guidelines
+const (
+	defaultSubtitleLineLength = 42
+	defaultSubtitleMaxLines   = 2
+)
+
+// How subtitle text is wrapped; zero values use the defaults
+type SubtitleOptions struct {
+	LineLength int `json:"lineLength"`
+	MaxLines   int `json:"maxLines"`
+}
+
+// One caption on screen
+type SubtitleCue struct {
+	Start float64
+	End   float64
+	Lines []string
+}
+
+var subtitleContentTypes = map[string]string{
+	"srt": "application/x-subrip; charset=utf-8",
+	"vtt": "text/vtt; charset=utf-8",
+}
+
+// Turn sentence segments into cues. Sentences that don't fit in MaxLines lines
+// are split over several cues, with the time divided by character count.
+func buildSubtitleCues(segments []engine.Segment, options SubtitleOptions) []SubtitleCue {
+	if options.LineLength <= 0 {
+		options.LineLength = defaultSubtitleLineLength
+	}
+	if options.MaxLines <= 0 {
+		options.MaxLines = defaultSubtitleMaxLines
+	}
+
+	cues := []SubtitleCue{}
+	for _, segment := range segments {
+		lines := wrapSubtitleText(segment.Text, options.LineLength)
+		if len(lines) == 0 {
+			continue
+		}
+
+		totalChars := 0
+		for _, line := range lines {
+			totalChars += utf8.RuneCountInString(line)
+		}
+
+		duration := segment.End - segment.Start
+		start := segment.Start
+		chars := 0
+		for i := 0; i < len(lines); i += options.MaxLines {
+			end := i + options.MaxLines
+			if end > len(lines) {
+				end = len(lines)
+			}
+
+			for _, line := range lines[i:end] {
+				chars += utf8.RuneCountInString(line)
+			}
+
+			cueEnd := segment.End
+			if end < len(lines) {
+				cueEnd = segment.Start + duration*float64(chars)/float64(totalChars)
+			}
+
+			cues = append(cues, SubtitleCue{Start: start, End: cueEnd, Lines: lines[i:end]})
+			start = cueEnd
+		}
+	}
+
+	return cues
+}
+
+// Wrap text at word boundaries into lines of at most lineLength characters.
+// Words longer than a line are kept whole.
+func wrapSubtitleText(text string, lineLength int) []string {
+	lines := []string{}
+	current := ""
+
+	for _, word := range strings.Fields(text) {
+		if current == "" {
+			current = word
+			continue
+		}
+		if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > lineLength {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+func formatSRT(cues []SubtitleCue) string {
+	var sb strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatSubtitleTime(cue.Start, ","), formatSubtitleTime(cue.End, ","),
+			strings.Join(cue.Lines, "\n"))
+	}
+	return sb.String()
+}
+
+func formatWebVTT(cues []SubtitleCue) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n",
+			formatSubtitleTime(cue.Start, "."), formatSubtitleTime(cue.End, "."),
+			strings.Join(cue.Lines, "\n"))
+	}
+	return sb.String()
+}
+
+// Render subtitles in "srt" or "vtt" format
+func formatSubtitles(format string, cues []SubtitleCue) (string, error) {
+	switch format {
+	case "srt":
+		return formatSRT(cues), nil
+	case "vtt":
+		return formatWebVTT(cues), nil
+	}
+	return "", fmt.Errorf("Subtitle format must be srt or vtt")
+}
+
+// HH:MM:SS,mmm (SRT) or HH:MM:SS.mmm (WebVTT)
+func formatSubtitleTime(seconds float64, separator string) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
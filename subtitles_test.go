@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopiper/engine"
+)
+
+func TestBuildSubtitleCues(t *testing.T) {
+	segments := []engine.Segment{
+		{Index: 0, Text: "Short one.", Start: 0, End: 1},
+		{Index: 1, Text: "aaaa bbbb cccc dddd", Start: 1.5, End: 3.5},
+	}
+
+	// The second sentence takes two cues, timed by character count
+	cues := buildSubtitleCues(segments, SubtitleOptions{LineLength: 10, MaxLines: 1})
+	want := []SubtitleCue{
+		{Start: 0, End: 1, Lines: []string{"Short one."}},
+		{Start: 1.5, End: 2.5, Lines: []string{"aaaa bbbb"}},
+		{Start: 2.5, End: 3.5, Lines: []string{"cccc dddd"}},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("got %+v, want %+v", cues, want)
+	}
+
+	srt := formatSRT(cues[:1])
+	if srt != "1\n00:00:00,000 --> 00:00:01,000\nShort one.\n\n" {
+		t.Errorf("SRT %q", srt)
+	}
+	vtt := formatWebVTT(cues[:1])
+	if vtt != "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nShort one.\n\n" {
+		t.Errorf("WebVTT %q", vtt)
+	}
+}
+
+func TestConvertSubtitlesShowSourceText(t *testing.T) {
+	setupTestEngine(t, nil)
+	if _, err := ttsEngine.Lexicons().Add("en", engine.ReplacementRule{Find: "GoPiper", Replace: "go piper", WholeWord: true}); err != nil {
+		t.Fatal(err)
+	}
+	model, err := ttsEngine.FindModelByID("en_US-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"text":      "GoPiper has 2 voices. It costs 3 dollars.",
+		"modelPath": model.OnnxPath,
+		"subtitles": map[string]interface{}{},
+	})
+	recorder := httptest.NewRecorder()
+	newRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var response struct {
+		Subtitles map[string]string `json:"subtitles"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"srt", "vtt"} {
+		captions := response.Subtitles[format]
+		for _, sentence := range []string{"GoPiper has 2 voices.", "It costs 3 dollars."} {
+			if !strings.Contains(captions, sentence) {
+				t.Errorf("%s captions don't show %q:\n%s", format, sentence, captions)
+			}
+		}
+	}
+}
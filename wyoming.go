@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/go-audio/audio"
+
+	"gopiper/engine"
+)
+
+// Wyoming protocol version we speak (https://github.com/rhasspy/wyoming)
+const wyomingVersion = "1.5.3"
+
+// Number of samples sent in each audio-chunk event
+const wyomingSamplesPerChunk = 1024
+
+// Largest header line (which may carry the data inline), data and payload accepted from a client
+const (
+	wyomingMaxDataLength    = 1 << 20
+	wyomingMaxPayloadLength = 16 << 20
+)
+
+type WyomingEvent struct {
+	Type    string
+	Data    map[string]interface{}
+	Payload []byte
+}
+
+// Header line that precedes the data and payload of every event
+type wyomingHeader struct {
+	Type          string                 `json:"type"`
+	Version       string                 `json:"version,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	DataLength    int                    `json:"data_length,omitempty"`
+	PayloadLength int                    `json:"payload_length,omitempty"`
+}
+
+type wyomingSynthesizeData struct {
+	Text  string `json:"text"`
+	Voice *struct {
+		Name     string `json:"name"`
+		Language string `json:"language"`
+		Speaker  string `json:"speaker"`
+	} `json:"voice"`
+}
+
+// Start the Wyoming TCP listener used by Home Assistant voice pipelines
+func startWyomingServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+
+	log.Printf("[WYOMING] ✅ Wyoming server listening on tcp://%s", addr)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Printf("[WYOMING] ❌ Accept failed: %v", err)
+				return
+			}
+			go handleWyomingConnection(conn)
+		}
+	}()
+
+	return nil
+}
+
+func handleWyomingConnection(conn net.Conn) {
+	defer conn.Close()
+	// A bad event must only cost its own connection, never the server
+	defer recoverWyomingPanic(conn)
+
+	log.Printf("[WYOMING] 🔌 Client connected: %s", conn.RemoteAddr())
+
+	// Cancelled once the client disconnects, which stops the synthesis it asked for
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Keep reading while a synthesis runs, so a disconnect is noticed right away
+	events := make(chan *WyomingEvent, 8)
+	go func() {
+		defer recoverWyomingPanic(conn)
+		defer close(events)
+		defer cancel()
+
+		reader := bufio.NewReader(conn)
+		for {
+			event, err := readWyomingEvent(reader)
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					log.Printf("[WYOMING] ❌ Error reading event: %v", err)
+				}
+				log.Printf("[WYOMING] 🔌 Client disconnected: %s", conn.RemoteAddr())
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for event := range events {
+		var err error
+		switch event.Type {
+		case "describe":
+			err = writeWyomingEvent(conn, &WyomingEvent{Type: "info", Data: wyomingInfo()})
+		case "ping":
+			err = writeWyomingEvent(conn, &WyomingEvent{Type: "pong", Data: event.Data})
+		case "synthesize":
+			err = handleWyomingSynthesize(ctx, conn, event)
+		default:
+			log.Printf("[WYOMING] ⚠️  Ignoring unsupported event: %s", event.Type)
+		}
+
+		if err != nil {
+			log.Printf("[WYOMING] ❌ Error handling %s: %v", event.Type, err)
+			return
+		}
+	}
+}
+
+func recoverWyomingPanic(conn net.Conn) {
+	if r := recover(); r != nil {
+		log.Printf("[WYOMING] ❌ Panic handling %s: %v", conn.RemoteAddr(), r)
+		conn.Close()
+	}
+}
+
+// Synthesize text and stream it back as audio-start, audio-chunk... and audio-stop events.
+// Cancelling ctx stops piper.
+func handleWyomingSynthesize(ctx context.Context, conn net.Conn, event *WyomingEvent) error {
+	var data wyomingSynthesizeData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return writeWyomingError(conn, "Invalid synthesize event", "invalid-request")
+	}
+
+	var voiceName, language, speaker string
+	if data.Voice != nil {
+		voiceName, language, speaker = data.Voice.Name, data.Voice.Language, data.Voice.Speaker
+	}
+
+	model, err := findWyomingVoice(voiceName, language)
+	if err != nil {
+		return writeWyomingError(conn, err.Error(), "voice-not-found")
+	}
+
+	log.Printf("[WYOMING] 🎤 Synthesize with %s: '%s'", model.ID, engine.TruncateString(data.Text, 100))
+
+	settings := map[string]interface{}{}
+	if speakerID, err := strconv.Atoi(speaker); err == nil {
+		settings["speaker"] = float64(speakerID)
+	}
+
+	conv, err := ttsEngine.Prepare(engine.ConvertRequest{
+		Text:      data.Text,
+		ModelPath: model.OnnxPath,
+		Options:   engine.Options{Settings: settings},
+	})
+	if err != nil {
+		return writeWyomingError(conn, err.Error(), "invalid-text")
+	}
+
+	started := false
+	timestamp := 0
+	totalFrames := 0
+
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	ctx = engine.WithClient(ctx, "ip:"+host)
+
+	err = ttsEngine.Stream(ctx, conv, func(index int, buffer *audio.IntBuffer, header *engine.WAVHeader) error {
+		format := map[string]interface{}{
+			"rate":     header.SampleRate,
+			"width":    header.BitsPerSample / 8,
+			"channels": header.NumChannels,
+		}
+
+		if !started {
+			if err := writeWyomingEvent(conn, &WyomingEvent{Type: "audio-start", Data: withTimestamp(format, timestamp)}); err != nil {
+				return err
+			}
+			started = true
+		}
+
+		chunkSize := wyomingSamplesPerChunk * int(header.NumChannels)
+		for start := 0; start < len(buffer.Data); start += chunkSize {
+			end := start + chunkSize
+			if end > len(buffer.Data) {
+				end = len(buffer.Data)
+			}
+
+			chunk := &audio.IntBuffer{Data: buffer.Data[start:end], Format: buffer.Format}
+			if err := writeWyomingEvent(conn, &WyomingEvent{
+				Type:    "audio-chunk",
+				Data:    withTimestamp(format, timestamp),
+				Payload: engine.PCMBytes(chunk, int(header.BitsPerSample)),
+			}); err != nil {
+				return err
+			}
+
+			totalFrames += (end - start) / int(header.NumChannels)
+			timestamp = totalFrames * 1000 / int(header.SampleRate)
+		}
+		return nil
+	})
+
+	if ctx.Err() != nil {
+		log.Printf("[WYOMING] 🛑 Synthesis stopped, the client disconnected")
+		return ctx.Err()
+	}
+	if err != nil {
+		log.Printf("[WYOMING] ❌ Synthesis failed: %v", err)
+		if !started {
+			return writeWyomingError(conn, err.Error(), "synthesis-failed")
+		}
+		// The client already received part of the audio; close the stream so it doesn't hang
+	}
+
+	log.Printf("[WYOMING] ✅ Sent %dms of audio", timestamp)
+	return writeWyomingEvent(conn, &WyomingEvent{Type: "audio-stop", Data: map[string]interface{}{"timestamp": timestamp}})
+}
+
+// Find the model for a Wyoming voice, by name first and then by language
+func findWyomingVoice(name, language string) (*engine.Model, error) {
+	if name != "" {
+		if model, err := ttsEngine.FindModelByID(name); err == nil {
+			return model, nil
+		}
+	}
+
+	models := ttsEngine.Models()
+	if language != "" {
+		for i := range models {
+			if models[i].Language == language {
+				return &models[i], nil
+			}
+		}
+	}
+
+	if name == "" && language == "" {
+		if defaultVoice := getEnv("WYOMING_VOICE", ""); defaultVoice != "" {
+			return ttsEngine.FindModelByID(defaultVoice)
+		}
+		if len(models) > 0 {
+			return &models[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("voice not found: %s", name)
+}
+
+// Build the info event describing every scanned model as a TTS voice
+func wyomingInfo() map[string]interface{} {
+	attribution := map[string]interface{}{
+		"name": "HirCoir",
+		"url":  "https://github.com/HirCoir/GoPiper",
+	}
+
+	voices := []map[string]interface{}{}
+	for _, model := range ttsEngine.Models() {
+		voices = append(voices, map[string]interface{}{
+			"name":        model.ID,
+			"description": model.Name,
+			"attribution": attribution,
+			"installed":   true,
+			"version":     nil,
+			"languages":   []string{model.Language},
+		})
+	}
+
+	return map[string]interface{}{
+		"asr":    []interface{}{},
+		"handle": []interface{}{},
+		"intent": []interface{}{},
+		"wake":   []interface{}{},
+		"tts": []map[string]interface{}{
+			{
+				"name":        "gopiper",
+				"description": "GoPiper text to speech",
+				"attribution": attribution,
+				"installed":   true,
+				"version":     wyomingVersion,
+				"voices":      voices,
+			},
+		},
+	}
+}
+
+func writeWyomingError(w io.Writer, text, code string) error {
+	return writeWyomingEvent(w, &WyomingEvent{
+		Type: "error",
+		Data: map[string]interface{}{"text": text, "code": code},
+	})
+}
+
+func withTimestamp(data map[string]interface{}, timestamp int) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		copied[key] = value
+	}
+	copied["timestamp"] = timestamp
+	return copied
+}
+
+// Read one event: a JSON header line followed by optional data and payload bytes
+func readWyomingEvent(r *bufio.Reader) (*WyomingEvent, error) {
+	line, err := readWyomingLine(r, wyomingMaxDataLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var header wyomingHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("invalid event header: %v", err)
+	}
+	if header.DataLength < 0 || header.DataLength > wyomingMaxDataLength {
+		return nil, fmt.Errorf("invalid event data length: %d", header.DataLength)
+	}
+	if header.PayloadLength < 0 || header.PayloadLength > wyomingMaxPayloadLength {
+		return nil, fmt.Errorf("invalid event payload length: %d", header.PayloadLength)
+	}
+
+	event := &WyomingEvent{Type: header.Type, Data: header.Data}
+	if event.Data == nil {
+		event.Data = map[string]interface{}{}
+	}
+
+	if header.DataLength > 0 {
+		dataBytes := make([]byte, header.DataLength)
+		if _, err := io.ReadFull(r, dataBytes); err != nil {
+			return nil, fmt.Errorf("error reading event data: %v", err)
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal(dataBytes, &data); err != nil {
+			return nil, fmt.Errorf("invalid event data: %v", err)
+		}
+		for key, value := range data {
+			event.Data[key] = value
+		}
+	}
+
+	if header.PayloadLength > 0 {
+		event.Payload = make([]byte, header.PayloadLength)
+		if _, err := io.ReadFull(r, event.Payload); err != nil {
+			return nil, fmt.Errorf("error reading event payload: %v", err)
+		}
+	}
+
+	return event, nil
+}
+
+// Read up to the next newline, giving up on lines longer than max bytes
+func readWyomingLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > max {
+			return nil, fmt.Errorf("event header longer than %d bytes", max)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// Write one event, sending data separately from the header as current Wyoming clients expect
+func writeWyomingEvent(w io.Writer, event *WyomingEvent) error {
+	header := wyomingHeader{
+		Type:          event.Type,
+		Version:       wyomingVersion,
+		PayloadLength: len(event.Payload),
+	}
+
+	var dataBytes []byte
+	if len(event.Data) > 0 {
+		var err error
+		dataBytes, err = json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		header.DataLength = len(dataBytes)
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	message := append(headerBytes, '\n')
+	message = append(message, dataBytes...)
+	message = append(message, event.Payload...)
+
+	_, err = w.Write(message)
+	return err
+}
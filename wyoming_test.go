@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gopiper/engine"
+)
+
+func TestReadWyomingEventRejectsBadLengths(t *testing.T) {
+	headers := []string{
+		`{"type":"synthesize","data_length":9223372036854775807}`,
+		`{"type":"synthesize","data_length":-1}`,
+		`{"type":"audio-chunk","payload_length":9223372036854775807}`,
+		`{"type":"audio-chunk","payload_length":-5}`,
+		`{"type":"synthesize","data":{"text":"` + strings.Repeat("a", wyomingMaxDataLength) + `"}}`,
+	}
+
+	for _, header := range headers {
+		reader := bufio.NewReader(strings.NewReader(header + "\n"))
+		if _, err := readWyomingEvent(reader); err == nil {
+			t.Errorf("accepted %.80s", header)
+		}
+	}
+}
+
+func TestReadWyomingEvent(t *testing.T) {
+	input := `{"type":"synthesize","data_length":16,"payload_length":3}` + "\n" + `{"text":"Hello"}` + "abc"
+	event, err := readWyomingEvent(bufio.NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "synthesize" || event.Data["text"] != "Hello" || string(event.Payload) != "abc" {
+		t.Errorf("got %+v", event)
+	}
+}
+
+// Connect a Wyoming client to a connection handler on a local port
+func dialTestWyoming(t *testing.T) net.Conn {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleWyomingConnection(conn)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+func TestWyomingRoundTrip(t *testing.T) {
+	setupTestEngine(t, nil)
+	conn := dialTestWyoming(t)
+	reader := bufio.NewReader(conn)
+
+	if err := writeWyomingEvent(conn, &WyomingEvent{Type: "describe"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := readWyomingEvent(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Type != "info" {
+		t.Fatalf("describe answered with %s", info.Type)
+	}
+	voices := info.Data["tts"].([]interface{})[0].(map[string]interface{})["voices"].([]interface{})
+	if len(voices) != 2 {
+		t.Errorf("info lists %d voices, want 2", len(voices))
+	}
+
+	text := "Hello from the test."
+	err = writeWyomingEvent(conn, &WyomingEvent{
+		Type: "synthesize",
+		Data: map[string]interface{}{"text": text, "voice": map[string]interface{}{"name": "en_US-test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := readWyomingEvent(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start.Type != "audio-start" {
+		t.Fatalf("got %s, want audio-start", start.Type)
+	}
+	if start.Data["rate"] != float64(22050) || start.Data["width"] != float64(2) || start.Data["channels"] != float64(1) {
+		t.Errorf("audio-start format %v, want 22050 Hz, 16-bit mono", start.Data)
+	}
+
+	chunks, payloadBytes := 0, 0
+	for {
+		event, err := readWyomingEvent(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type == "audio-stop" {
+			// The fake backend reads every character in 10 ms
+			wantFrames := len(text) * 22050 / 100
+			if frames := payloadBytes / 2; frames < wantFrames-10 || frames > wantFrames+10 {
+				t.Errorf("got %d frames of audio, want about %d", frames, wantFrames)
+			}
+			if stop := int(event.Data["timestamp"].(float64)); stop != payloadBytes/2*1000/22050 {
+				t.Errorf("audio-stop at %d ms for %d frames", stop, payloadBytes/2)
+			}
+			break
+		}
+		if event.Type != "audio-chunk" {
+			t.Fatalf("got %s while streaming audio", event.Type)
+		}
+		chunks++
+		payloadBytes += len(event.Payload)
+	}
+	if chunks < 2 {
+		t.Errorf("audio came in %d chunks, want it split", chunks)
+	}
+}
+
+// Blocks every sentence until its context is cancelled
+type blockingBackend struct {
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (bb *blockingBackend) Synthesize(ctx context.Context, text, modelPath string, settings engine.AudioSettings) (string, error) {
+	bb.started <- struct{}{}
+	<-ctx.Done()
+	close(bb.cancelled)
+	return "", ctx.Err()
+}
+
+func TestWyomingDisconnectStopsSynthesis(t *testing.T) {
+	backend := &blockingBackend{started: make(chan struct{}, 1), cancelled: make(chan struct{})}
+	setupTestEngine(t, backend)
+	conn := dialTestWyoming(t)
+
+	err := writeWyomingEvent(conn, &WyomingEvent{
+		Type: "synthesize",
+		Data: map[string]interface{}{"text": "This will never be read.", "voice": map[string]interface{}{"name": "en_US-test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-backend.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("synthesis never started")
+	}
+
+	conn.Close()
+
+	select {
+	case <-backend.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("synthesis kept running after the client disconnected")
+	}
+}